@@ -0,0 +1,72 @@
+package getopt
+
+//Aliases are additional long names for an option, e.g., --colour
+//for --color.  Passing an alias sets the option itself.  In the
+//help they are listed with the option, and they are not given in
+//the usage synopsis
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (f *Flag) AddAlias(long string) *Flag {
+	f.parser.addAlias(f, long)
+	return f
+}
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (o *OptArg) AddAlias(long string) *OptArg {
+	o.parser.addAlias(o, long)
+	return o
+}
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (v *OptVec) AddAlias(long string) *OptVec {
+	v.parser.addAlias(v, long)
+	return v
+}
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (c *OptCount) AddAlias(long string) *OptCount {
+	c.parser.addAlias(c, long)
+	return c
+}
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (n *OptInt) AddAlias(long string) *OptInt {
+	n.parser.addAlias(n, long)
+	return n
+}
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (x *OptFloat) AddAlias(long string) *OptFloat {
+	x.parser.addAlias(x, long)
+	return x
+}
+
+//Add another long name for the option.  Panics if the name is
+//taken
+func (d *OptDuration) AddAlias(long string) *OptDuration {
+	d.parser.addAlias(d, long)
+	return d
+}
+
+//Add another long name for an option
+func (p *Parser) addAlias(opt Option, long string) {
+	p.checkLong(long)
+	p.optByLong[long] = opt
+	p.aliases[opt] = append(p.aliases[opt], long)
+}
+
+//Whether a long name is an alias of the option
+func (p *Parser) isAlias(opt Option, long string) bool {
+	for _, alias := range p.aliases[opt] {
+		if alias == long {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package getopt
+
+import(
+	"bytes"
+	"testing"
+)
+
+//Check that every alias drives the same option
+func TestAliases(t *testing.T) {
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more").AddAlias("debug")
+	c := p.NewOptArg('c', "color", "when to color").AddAlias("colour")
+	if err := p.ParseArgv([]string{ "--debug", "--colour=never" }); err != nil {
+		t.Fatal(err)
+	}
+	if !v.Passed || c.Opt != "never" {
+		t.Fatalf("Expected aliases to set the options, got %t '%s'", v.Passed, c.Opt)
+	}
+	if err := p.ParseArgv([]string{ "--no-debug", "--color", "always" }); err != nil {
+		t.Fatal(err)
+	}
+	if v.Passed || c.Opt != "always" {
+		t.Fatalf("Expected --no-debug and --color, got %t '%s'", v.Passed, c.Opt)
+	}
+	if err := p.AssertInvariants(); err != nil {
+		t.Fatalf("Expected consistent registry, got %s", err)
+	}
+}
+
+//Check that aliases are listed with their option, not on their own
+func TestAliasHelp(t *testing.T) {
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+	p.NewOptArg('c', "color", "when to color").AddAlias("colour")
+	p.NewFlag('v', "verbose", "print more")
+	expected := "prog - 1.0\n" +
+		"Does things\n" +
+		"-c/--color, --colour  when to color\n" +
+		"-v/--verbose          print more\n"
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+	if u := p.Usage(); u != "usage: prog [-c COLOR] [-v] [args...]" {
+		t.Fatalf("Expected alias left out of usage, got '%s'", u)
+	}
+}
+
+//Check that an alias may not take a name in use
+func TestAliasDuplicate(t *testing.T) {
+	p := NewParser()
+	c := p.NewOptArg('c', "color", "when to color")
+	p.NewFlag(0, "colour", "a different option")
+	defer func() {
+		if r := recover(); r != "Duplicate long option --colour" {
+			t.Fatalf("Expected panic for taken alias, got %v", r)
+		}
+	}()
+	c.AddAlias("colour")
+}
@@ -0,0 +1,36 @@
+package getopt
+
+import(
+	"os"
+	"strings"
+)
+
+//Byte that introduces an argument file, e.g., "@args.txt".  The
+//file is read and the whitespace separated words in it take the
+//place of the argument, before any other parsing.  Words are not
+//expanded again, and arguments after "--" are left alone.  Set
+//to 0 to take such arguments literally
+var ArgFilePrefix byte = '@'
+
+//Replace each argument file in argv with the words in it
+func expandArgFiles(argv []string) ([]string, error) {
+	if ArgFilePrefix == 0 {
+		return argv, nil
+	}
+	expanded := make([]string, 0, len(argv))
+	for i, arg := range argv {
+		if arg == longPrefix() {
+			return append(expanded, argv[i:]...), nil
+		}
+		if len(arg) < 2 || arg[0] != ArgFilePrefix {
+			expanded = append(expanded, arg)
+			continue
+		}
+		contents, err := os.ReadFile(arg[1:])
+		if err != nil {
+			return nil, usageErrorf("Unable to read argument file %s:  %w", arg[1:], err)
+		}
+		expanded = append(expanded, strings.Fields(string(contents))...)
+	}
+	return expanded, nil
+}
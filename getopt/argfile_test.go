@@ -0,0 +1,72 @@
+package getopt
+
+import(
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//Check that the words in an argument file are parsed in its place
+func TestArgFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args")
+	if err := os.WriteFile(path, []byte("-v --file in.txt\n-I a\n  -Ib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewParser()
+	v := p.NewOptCount('v', "verbose", "verbosity")
+	f := p.NewOptArg('f', "file", "file to read")
+	inc := p.NewOptVec('I', "include", "include directory")
+	if err := p.ParseArgv([]string{ "-v", "@" + path, "--file", "out.txt", "x", "--", "@" + path }); err != nil {
+		t.Fatal(err)
+	}
+	if v.Count != 2 || f.Opt != "out.txt" || len(inc.OptArgs) != 2 || inc.OptArgs[1] != "b" {
+		t.Fatalf("Expected the file's options applied in place, got %d '%s' %v", v.Count, f.Opt, inc.OptArgs)
+	}
+	if len(p.Rest) != 2 || p.Rest[1] != "@" + path {
+		t.Fatalf("Expected the argument after -- left alone, got %v", p.Rest)
+	}
+}
+
+//Check that a missing argument file is an error, unless disabled
+func TestArgFileMissing(t *testing.T) {
+	p := NewParser()
+	missing := filepath.Join(t.TempDir(), "missing")
+	err := p.ParseArgv([]string{ "@" + missing })
+	if !errors.Is(err, os.ErrNotExist) || !errors.As(err, new(*UsageError)) {
+		t.Fatalf("Expected a usage error for the missing file, got %v", err)
+	}
+	ArgFilePrefix = 0
+	defer func() { ArgFilePrefix = '@' }()
+	if err := p.ParseArgv([]string{ "@" + missing, "@" }); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Rest) != 2 || p.Rest[0] != "@" + missing {
+		t.Fatalf("Expected the arguments taken literally, got %v", p.Rest)
+	}
+}
+
+//Check that the arguments of a command are expanded only once, so a
+//word from a file naming another file is taken literally
+func TestArgFileCommand(t *testing.T) {
+	dir := t.TempDir()
+	inner, outer := filepath.Join(dir, "inner"), filepath.Join(dir, "outer")
+	if err := os.WriteFile(inner, []byte("--bogus\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(outer, []byte("push @" + inner + "\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewParser()
+	var args []string
+	p.AddCommand("push", "send changes", func(a []string) error {
+		args = a
+		return nil
+	})
+	if err := p.Dispatch([]string{ "@" + outer }); err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 1 || args[0] != "@" + inner {
+		t.Fatalf("Expected @%s taken literally, got %v", inner, args)
+	}
+}
@@ -0,0 +1,156 @@
+package getopt
+
+import(
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+//Type of time.Duration fields, which are bound to OptDurations
+var durationType = reflect.TypeOf(time.Duration(0))
+
+//A struct field bound to an option by Bind
+type binding struct {
+	field	reflect.Value
+	opt	Option
+}
+
+//A field of a struct passed to Bind, and how to register it
+type bindField struct {
+	field	reflect.Value
+	short	byte
+	long	string
+	help	string
+	count	bool
+}
+
+//Register an option for each field of the struct v points to that
+//has a getopt tag, and write the parsed values back to the fields
+//after each ParseArgv.  The tag holds the short and long names and
+//optional modifiers, e.g.:
+//
+//	Verbose	int	`getopt:"v,verbose,count" help:"print more"`
+//	Output	string	`getopt:",output"`
+//
+//bool fields become Flags, string fields OptArgs, []string fields
+//OptVecs, int and int64 fields OptInts, or OptCounts with the
+//count modifier, float64 fields OptFloats and time.Duration fields
+//OptDurations.  The values in the fields when Bind is called are
+//the defaults.  Nothing is registered if any tag or field type is
+//invalid
+func Bind(v any) error {
+	return defaultParser.Bind(v)
+}
+
+//Register an option for each field of the struct v points to that
+//has a getopt tag, and write the parsed values back to the fields
+//after each ParseArgv.  The tag holds the short and long names and
+//optional modifiers, e.g.:
+//
+//	Verbose	int	`getopt:"v,verbose,count" help:"print more"`
+//	Output	string	`getopt:",output"`
+//
+//bool fields become Flags, string fields OptArgs, []string fields
+//OptVecs, int and int64 fields OptInts, or OptCounts with the
+//count modifier, float64 fields OptFloats and time.Duration fields
+//OptDurations.  The values in the fields when Bind is called are
+//the defaults.  Nothing is registered if any tag or field type is
+//invalid
+func (p *Parser) Bind(v any) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Bind needs a pointer to a struct, got %T", v)
+	}
+	s := ptr.Elem()
+	fields := make([]bindField, 0, s.NumField())
+	for i := 0; i < s.NumField(); i++ {
+		sf := s.Type().Field(i)
+		tag, ok := sf.Tag.Lookup("getopt")
+		if !ok || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		if len(parts) < 2 || len(parts[0]) > 1 || parts[1] == "" {
+			return fmt.Errorf("Invalid getopt tag %q for field %s, expected \"SHORT,LONG\"", tag, sf.Name)
+		}
+		f := bindField{
+			field:	s.Field(i),
+			long:	parts[1],
+			help:	sf.Tag.Get("help"),
+		}
+		if parts[0] != "" {
+			f.short = parts[0][0]
+		}
+		for _, mod := range parts[2:] {
+			if mod != "count" {
+				return fmt.Errorf("Unknown getopt tag modifier %s for field %s", mod, sf.Name)
+			}
+			f.count = true
+		}
+		if !sf.IsExported() {
+			return fmt.Errorf("Field %s is not exported", sf.Name)
+		}
+		switch sf.Type.Kind() {
+		case reflect.Bool, reflect.String, reflect.Float64:
+		case reflect.Int, reflect.Int64:
+		case reflect.Slice:
+			if sf.Type.Elem().Kind() != reflect.String {
+				return fmt.Errorf("Unsupported type %s for field %s", sf.Type, sf.Name)
+			}
+		default:
+			return fmt.Errorf("Unsupported type %s for field %s", sf.Type, sf.Name)
+		}
+		if f.count && ((sf.Type.Kind() != reflect.Int && sf.Type.Kind() != reflect.Int64) || sf.Type == durationType) {
+			return fmt.Errorf("The count modifier needs an integer field, but %s is %s", sf.Name, sf.Type)
+		}
+		fields = append(fields, f)
+	}
+	for _, f := range fields {
+		p.bindings = append(p.bindings, binding{ f.field, p.bindOpt(f) })
+	}
+	return nil
+}
+
+//Register the option for a field, taking its value as the default
+func (p *Parser) bindOpt(f bindField) Option {
+	switch f.field.Kind() {
+	case reflect.Bool:
+		o := p.NewFlag(f.short, f.long, f.help)
+		o.Passed = f.field.Bool()
+		return o
+	case reflect.String:
+		return p.NewOptArgDefault(f.short, f.long, f.help, f.field.String())
+	case reflect.Slice:
+		o := p.NewOptVec(f.short, f.long, f.help)
+		if f.field.Len() > 0 {
+			o.SetDefault(f.field.Interface().([]string))
+		}
+		return o
+	case reflect.Float64:
+		o := p.NewOptFloat(f.short, f.long, f.help)
+		o.Value = f.field.Float()
+		return o
+	default:
+		if f.field.Type() == durationType {
+			o := p.NewOptDuration(f.short, f.long, f.help)
+			o.Value = time.Duration(f.field.Int())
+			return o
+		}
+		if f.count {
+			o := p.NewOptCount(f.short, f.long, f.help)
+			o.Count = f.field.Int()
+			return o
+		}
+		o := p.NewOptInt(f.short, f.long, f.help)
+		o.Value = f.field.Int()
+		return o
+	}
+}
+
+//Write the values of the bound options back to their fields
+func (p *Parser) writeBindings() {
+	for _, b := range p.bindings {
+		b.field.Set(reflect.ValueOf(optValue(b.opt)).Convert(b.field.Type()))
+	}
+}
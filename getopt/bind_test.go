@@ -0,0 +1,78 @@
+package getopt
+
+import(
+	"fmt"
+	"strings"
+	"testing"
+)
+
+//Check that parsed values land in the bound struct
+func TestBind(t *testing.T) {
+	var opts struct {
+		Quiet	bool		`getopt:"q,quiet" help:"print less"`
+		Output	string		`getopt:",output"`
+		Include	[]string	`getopt:"I,include"`
+		Verbose	int		`getopt:"v,verbose,count"`
+		Port	int64		`getopt:"p,port"`
+		Rate	float64		`getopt:"r,rate"`
+		Ignored	string
+	}
+	opts.Output = "out.txt"
+	opts.Port = 80
+	p := NewParser()
+	if err := p.Bind(&opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Options()) != 6 {
+		t.Fatalf("Expected 6 options, got %d", len(p.Options()))
+	}
+	if f, _ := p.optByLong["quiet"].(*Flag); f == nil || f.Help != "print less" || f.Short != 'q' {
+		t.Fatal("Expected -q/--quiet with its help")
+	}
+	if err := p.ParseArgv([]string{ "-q", "-Ia", "-Ib", "-vvv", "-r", "0.5", "x" }); err != nil {
+		t.Fatal(err)
+	}
+	got := fmt.Sprintf("%t %s %v %d %d %g", opts.Quiet, opts.Output, opts.Include, opts.Verbose, opts.Port, opts.Rate)
+	if got != "true out.txt [a b] 3 80 0.5" {
+		t.Fatalf("Expected the parsed values and defaults, got %s", got)
+	}
+	if err := p.ParseArgv([]string{ "--output=o", "--port", "8080" }); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Output != "o" || opts.Port != 8080 {
+		t.Fatalf("Expected o and 8080, got %s %d", opts.Output, opts.Port)
+	}
+}
+
+//Check that bad structs are rejected without registering anything
+func TestBindErrors(t *testing.T) {
+	var unsupported struct {
+		Name	string		`getopt:"n,name"`
+		Size	uint		`getopt:"s,size"`
+	}
+	var badTag struct {
+		Name	string		`getopt:"name"`
+	}
+	var badCount struct {
+		Name	string		`getopt:"n,name,count"`
+	}
+	cases := []struct {
+		v	any
+		msg	string
+	}{
+		{ &unsupported, "Unsupported type uint for field Size" },
+		{ &badTag, "Invalid getopt tag" },
+		{ &badCount, "The count modifier needs an integer field" },
+		{ unsupported, "Bind needs a pointer to a struct" },
+	}
+	for _, c := range cases {
+		p := NewParser()
+		err := p.Bind(c.v)
+		if err == nil || !strings.HasPrefix(err.Error(), c.msg) {
+			t.Fatalf("Expected %q, got %v", c.msg, err)
+		}
+		if len(p.Options()) != 0 {
+			t.Fatalf("Expected nothing registered for %q", c.msg)
+		}
+	}
+}
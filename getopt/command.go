@@ -0,0 +1,107 @@
+package getopt
+
+import(
+	"fmt"
+	"sort"
+)
+
+//A subcommand, as in "git commit", with its own options.  The
+//options are registered on the embedded Parser, e.g.,
+//cmd.NewFlag('a', "all", "commit all changes")
+type Command struct {
+	//Name of the command, as passed on the command line
+	Name	string
+	//Description of the command
+	Desc	string
+	//Called by Dispatch with the arguments left after parsing
+	//the command's options
+	Run	func(args []string) error	`json:"-"`
+	*Parser
+}
+
+//Register a command to be run by Dispatch.  Panics if a command
+//with the same name was already registered
+func AddCommand(name, desc string, run func(args []string) error) *Command {
+	return defaultParser.AddCommand(name, desc, run)
+}
+
+//Register a command to be run by Dispatch.  Panics if a command
+//with the same name was already registered
+func (p *Parser) AddCommand(name, desc string, run func(args []string) error) *Command {
+	if _, ok := p.commands[name]; ok {
+		panic(fmt.Sprintf("Duplicate command %s", name))
+	}
+	cmd := &Command{
+		Name:	name,
+		Desc:	desc,
+		Run:	run,
+		Parser:	NewParser(),
+	}
+	cmd.ProgramDesc = desc
+	p.commands[name] = cmd
+	return cmd
+}
+
+//Return the names of the registered commands, sorted
+func (p *Parser) commandNames() []string {
+	names := make([]string, 0, len(p.commands))
+	for name := range p.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+//Parse the global options up to the first non-option, which names
+//the command, then parse the remaining arguments against that
+//command's options and call its Run with what is left
+func Dispatch(argv []string) error {
+	defaultParser.mu.Lock()
+	loadDefault()
+	defaultParser.mu.Unlock()
+	return defaultParser.dispatch(argv, func() {
+		Rest = defaultParser.Rest
+		OptInd = defaultParser.OptInd
+	})
+}
+
+//Parse the global options up to the first non-option, which names
+//the command, then parse the remaining arguments against that
+//command's options and call its Run with what is left
+func (p *Parser) Dispatch(argv []string) error {
+	return p.dispatch(argv, func() {})
+}
+
+//Dispatch the arguments to a command, calling parsed with the
+//parser still locked once the global options are parsed
+func (p *Parser) dispatch(argv []string, parsed func()) error {
+	p.mu.Lock()
+	p.inOrder = true
+	err := p.parse(argv, false)
+	p.inOrder = false
+	parsed()
+	rest := p.Rest
+	name := p.completionName()
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 {
+		return &UsageError{ &UnknownCommandError{ "", p.commandNames() } }
+	}
+	cmd, ok := p.commands[rest[0]]
+	if !ok {
+		return &UsageError{ &UnknownCommandError{ rest[0], p.commandNames() } }
+	}
+	if cmd.ProgramName == "" {
+		cmd.ProgramName = name + " " + cmd.Name
+	}
+	//The argument files were expanded with the global options
+	cmd.mu.Lock()
+	err = cmd.parseExpanded(rest[1:], false)
+	cmd.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return cmd.Run(cmd.Rest)
+}
@@ -0,0 +1,75 @@
+package getopt
+
+import(
+	"errors"
+	"fmt"
+	"testing"
+)
+
+//Check that Dispatch runs the named command with its own options
+func TestDispatch(t *testing.T) {
+	p := NewParser()
+	verbose := p.NewFlag('v', "verbose", "print more")
+	ran := ""
+	var args []string
+	clone := p.AddCommand("clone", "copy a repository", func(a []string) error {
+		ran, args = "clone", a
+		return nil
+	})
+	depth := clone.NewOptInt('d', "depth", "history to fetch")
+	commit := p.AddCommand("commit", "record changes", func(a []string) error {
+		ran, args = "commit", a
+		return nil
+	})
+	msg := commit.NewOptArg('m', "message", "commit message")
+	all := commit.NewFlag('v', "all", "commit all changes")
+
+	if err := p.Dispatch([]string{ "-v", "clone", "--depth", "1", "url", "--verbose" }); err == nil {
+		t.Fatal("Expected --verbose after clone to be unknown to clone")
+	}
+	if err := p.Dispatch([]string{ "-v", "clone", "--depth", "1", "url" }); err != nil {
+		t.Fatal(err)
+	}
+	if ran != "clone" || !verbose.Passed || depth.Value != 1 || fmt.Sprint(args) != "[url]" {
+		t.Fatalf("Expected clone with --depth 1 url, got %s %t %d %v", ran, verbose.Passed, depth.Value, args)
+	}
+	if err := p.Dispatch([]string{ "commit", "-v", "-m", "fix", "--", "-x" }); err != nil {
+		t.Fatal(err)
+	}
+	if ran != "commit" || !all.Passed || msg.Opt != "fix" || fmt.Sprint(args) != "[-x]" {
+		t.Fatalf("Expected commit -v -m fix, got %s %t '%s' %v", ran, all.Passed, msg.Opt, args)
+	}
+	if commit.ProgramName == "" || p.OptInd != 0 {
+		t.Fatalf("Expected the command's program name and OptInd 0, got '%s' %d", commit.ProgramName, p.OptInd)
+	}
+}
+
+//Check the errors for a missing or unknown command
+func TestDispatchUnknown(t *testing.T) {
+	p := NewParser()
+	p.AddCommand("push", "send changes", func([]string) error { return nil })
+	p.AddCommand("clone", "copy a repository", func([]string) error { return nil })
+	cases := []struct {
+		argv	[]string
+		msg	string
+	}{
+		{ []string{}, "Missing command, expected one of:  clone, push" },
+		{ []string{ "pull" }, "Unknown command pull, expected one of:  clone, push" },
+	}
+	for _, c := range cases {
+		err := p.Dispatch(c.argv)
+		var cmdErr *UnknownCommandError
+		if !errors.As(err, &cmdErr) || err.Error() != c.msg {
+			t.Fatalf("%v: expected %q, got %v", c.argv, c.msg, err)
+		}
+		if !errors.As(err, new(*UsageError)) {
+			t.Fatalf("%v: expected a usage error, got %T", c.argv, err)
+		}
+	}
+	defer func() {
+		if r := recover(); r != "Duplicate command push" {
+			t.Fatalf("Expected panic for a duplicate command, got %v", r)
+		}
+	}()
+	p.AddCommand("push", "again", nil)
+}
@@ -0,0 +1,305 @@
+package getopt
+
+import(
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//The first argument of a completion request.  The generated shell
+//scripts run the program as:
+//
+//	prog __complete --shell=NAME -- WORDS...
+//
+//where WORDS are the words typed after the program name, the last
+//being the word under the cursor.  The program should pass its
+//arguments to Complete before parsing them
+const CompleteCommand = "__complete"
+
+//A possible completion and its description
+type candidate struct {
+	word	string
+	desc	string
+}
+
+//If argv is a completion request, write the completion candidates
+//to w, one per line, and return true.  Otherwise return false
+//without writing anything.  Candidates are formatted for the shell
+//named by --shell:  "bash" gives only the words, "zsh" gives
+//word:description and "fish" and "powershell" give
+//word<tab>description
+func Complete(w io.Writer, argv []string) bool {
+	return defaultParser.Complete(w, argv)
+}
+
+//If argv is a completion request, write the completion candidates
+//to w, one per line, and return true.  Otherwise return false
+//without writing anything.  Candidates are formatted for the shell
+//named by --shell:  "bash" gives only the words, "zsh" gives
+//word:description and "fish" and "powershell" give
+//word<tab>description
+func (p *Parser) Complete(w io.Writer, argv []string) bool {
+	if len(argv) == 0 || argv[0] != CompleteCommand {
+		return false
+	}
+	argv = argv[1:]
+	shell := "bash"
+	if len(argv) > 0 && strings.HasPrefix(argv[0], "--shell=") {
+		shell = argv[0][len("--shell="):]
+		argv = argv[1:]
+	}
+	if len(argv) > 0 && argv[0] == "--" {
+		argv = argv[1:]
+	}
+	current := ""
+	if len(argv) > 0 {
+		current = argv[len(argv) - 1]
+		argv = argv[:len(argv) - 1]
+	}
+	for _, c := range p.candidates(argv, current) {
+		switch shell {
+		case "zsh":
+			if c.desc == "" {
+				fmt.Fprintln(w, strings.ReplaceAll(c.word, ":", "\\:"))
+			} else {
+				fmt.Fprintf(w, "%s:%s\n", strings.ReplaceAll(c.word, ":", "\\:"), c.desc)
+			}
+		case "fish", "powershell":
+			if c.desc == "" {
+				fmt.Fprintln(w, c.word)
+			} else {
+				fmt.Fprintf(w, "%s\t%s\n", c.word, c.desc)
+			}
+		default:
+			fmt.Fprintln(w, c.word)
+		}
+	}
+	return true
+}
+
+//The option still waiting for its argument at the end of the
+//word, e.g., "--file" or "-vf" where f takes an argument, or nil
+func (p *Parser) waitingOpt(word string) Option {
+	if len(word) < 2 || word[0] != OptionPrefix {
+		return nil
+	}
+	if word[1] == OptionPrefix {
+		if strings.IndexByte(word, '=') != -1 {
+			return nil
+		}
+		if v, ok := p.optByLong[word[2:]]; ok && takesArg(v) {
+			return v
+		}
+		return nil
+	}
+	for i := 1; i < len(word); i++ {
+		if v, ok := p.optByShort[word[i]]; ok && takesArg(v) {
+			if i == len(word) - 1 {
+				return v
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+//Whether the word is an option still waiting for its argument,
+//e.g., "--file" or "-vf" where f takes an argument
+func (p *Parser) expectsValue(word string) bool {
+	return p.waitingOpt(word) != nil
+}
+
+//The values an option's argument is restricted to, if any
+func optChoices(opt Option) []string {
+	if o, ok := opt.(*OptArg); ok {
+		return o.Choices
+	}
+	return nil
+}
+
+//Find the candidates for the word under the cursor, given the
+//words before it
+func (p *Parser) candidates(prev []string, current string) []candidate {
+	if len(prev) > 0 && p.expectsValue(prev[len(prev) - 1]) {
+		cands := make([]candidate, 0, initialCapacity)
+		for _, c := range optChoices(p.waitingOpt(prev[len(prev) - 1])) {
+			if strings.HasPrefix(c, current) {
+				cands = append(cands, candidate{ c, "" })
+			}
+		}
+		return cands
+	}
+	if !strings.HasPrefix(current, string(OptionPrefix)) {
+		return nil
+	}
+	cands := make([]candidate, 0, initialCapacity)
+	if current == string(OptionPrefix) {
+		shorts := make([]int, 0, len(p.optByShort))
+		for s, opt := range p.optByShort {
+			if !optHidden(opt) {
+				shorts = append(shorts, int(s))
+			}
+		}
+		sort.Ints(shorts)
+		for _, s := range shorts {
+			opt := p.optByShort[byte(s)]
+			cands = append(cands, candidate{ string([]byte{ OptionPrefix, byte(s) }), optHelp(opt) })
+		}
+	}
+	for _, long := range p.completionLongs() {
+		if strings.HasPrefix(longPrefix() + long, current) {
+			cands = append(cands, candidate{ longPrefix() + long, optHelp(p.optByLong[long]) })
+		}
+	}
+	return cands
+}
+
+//Name of the program, as used to invoke it from the shell
+func (p *Parser) completionName() string {
+	name := p.ProgramName
+	if name == "" {
+		name = os.Args[0]
+	}
+	return filepath.Base(name)
+}
+
+//Name of the program made safe for use as a shell function name
+func (p *Parser) completionFunc() string {
+	return "_" + strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, p.completionName()) + "_complete"
+}
+
+//Write a bash completion script for the program.  The script
+//asks the program itself for candidates, falling back on the
+//option names and choices registered when the script was written
+//if the program fails
+func GenBashCompletion(w io.Writer) {
+	loadDefault()
+	defaultParser.GenBashCompletion(w)
+}
+
+//Write a bash completion script for the program.  The script
+//asks the program itself for candidates, falling back on the
+//option names and choices registered when the script was written
+//if the program fails
+func (p *Parser) GenBashCompletion(w io.Writer) {
+	name, fn := p.completionName(), p.completionFunc()
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal IFS=$'\\n'\n")
+	fmt.Fprintf(w, "\tCOMPREPLY=($(%s %s --shell=bash -- \"${COMP_WORDS[@]:1:COMP_CWORD}\")) && return\n", name, CompleteCommand)
+	fmt.Fprintf(w, "\tIFS=$' \\t\\n'\n")
+	fmt.Fprintf(w, "\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	fmt.Fprintf(w, "\tcase \"$prev\" in\n")
+	for _, long := range p.completionLongs() {
+		opt := p.optByLong[long]
+		choices := optChoices(opt)
+		if len(choices) == 0 {
+			continue
+		}
+		pattern := longPrefix() + long
+		if optShort(opt) != 0 {
+			pattern += "|" + string([]byte{ OptionPrefix, optShort(opt) })
+		}
+		fmt.Fprintf(w, "\t%s) COMPREPLY=($(compgen -W %s -- \"$cur\")); return;;\n", pattern, shellQuote(strings.Join(choices, " ")))
+	}
+	fmt.Fprintf(w, "\tesac\n")
+	words := make([]string, 0, len(p.optByLong) + len(p.optByShort))
+	for _, long := range p.completionLongs() {
+		words = append(words, longPrefix() + long)
+	}
+	shorts := make([]int, 0, len(p.optByShort))
+	for s, opt := range p.optByShort {
+		if !optHidden(opt) {
+			shorts = append(shorts, int(s))
+		}
+	}
+	sort.Ints(shorts)
+	for _, s := range shorts {
+		words = append(words, string([]byte{ OptionPrefix, byte(s) }))
+	}
+	fmt.Fprintf(w, "\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(words, " ")))
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "complete -o default -F %s %s\n", fn, name)
+}
+
+//Every registered long name, including aliases, sorted, except
+//those of hidden options
+func (p *Parser) completionLongs() []string {
+	longs := make([]string, 0, len(p.optByLong))
+	for long, opt := range p.optByLong {
+		if !optHidden(opt) {
+			longs = append(longs, long)
+		}
+	}
+	sort.Strings(longs)
+	return longs
+}
+
+//Quote a string for the shell with single quotes
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+//Write a zsh completion script for the program.  The script
+//asks the program itself for candidates
+func GenZshCompletion(w io.Writer) {
+	loadDefault()
+	defaultParser.GenZshCompletion(w)
+}
+
+//Write a zsh completion script for the program.  The script
+//asks the program itself for candidates
+func (p *Parser) GenZshCompletion(w io.Writer) {
+	name, fn := p.completionName(), p.completionFunc()
+	fmt.Fprintf(w, "#compdef %s\n", name)
+	fmt.Fprintf(w, "%s() {\n", fn)
+	fmt.Fprintf(w, "\tlocal -a candidates\n")
+	fmt.Fprintf(w, "\tcandidates=(\"${(@f)$(%s %s --shell=zsh -- \"${(@)words[2,CURRENT]}\")}\")\n", name, CompleteCommand)
+	fmt.Fprintf(w, "\t_describe 'option' candidates\n")
+	fmt.Fprintf(w, "}\n")
+	fmt.Fprintf(w, "compdef %s %s\n", fn, name)
+}
+
+//Write a fish completion script for the program.  The script
+//asks the program itself for candidates
+func GenFishCompletion(w io.Writer) {
+	loadDefault()
+	defaultParser.GenFishCompletion(w)
+}
+
+//Write a fish completion script for the program.  The script
+//asks the program itself for candidates
+func (p *Parser) GenFishCompletion(w io.Writer) {
+	name := p.completionName()
+	fmt.Fprintf(w, "complete -c %s -a '(%s %s --shell=fish -- (commandline -opc)[2..-1] (commandline -ct))'\n", name, name, CompleteCommand)
+}
+
+//Write a PowerShell completion script for the program.  The script
+//asks the program itself for candidates
+func GenPowerShellCompletion(w io.Writer) {
+	loadDefault()
+	defaultParser.GenPowerShellCompletion(w)
+}
+
+//Write a PowerShell completion script for the program.  The script
+//asks the program itself for candidates
+func (p *Parser) GenPowerShellCompletion(w io.Writer) {
+	name := p.completionName()
+	fmt.Fprintf(w, "Register-ArgumentCompleter -Native -CommandName '%s' -ScriptBlock {\n", name)
+	fmt.Fprintf(w, "\tparam($wordToComplete, $commandAst, $cursorPosition)\n")
+	fmt.Fprintf(w, "\t$words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })\n")
+	fmt.Fprintf(w, "\tif ($wordToComplete -eq '') { $words += '' }\n")
+	fmt.Fprintf(w, "\t& '%s' %s --shell=powershell -- @words | ForEach-Object {\n", name, CompleteCommand)
+	fmt.Fprintf(w, "\t\t$word, $desc = $_ -split \"`t\", 2\n")
+	fmt.Fprintf(w, "\t\tif (-not $desc) { $desc = $word }\n")
+	fmt.Fprintf(w, "\t\t[System.Management.Automation.CompletionResult]::new($word, $word, 'ParameterName', $desc)\n")
+	fmt.Fprintf(w, "\t}\n")
+	fmt.Fprintf(w, "}\n")
+}
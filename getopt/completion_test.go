@@ -0,0 +1,158 @@
+package getopt
+
+import(
+	"bytes"
+	"strings"
+	"testing"
+)
+
+//Check that the responder formats candidates for each shell
+func TestCompleteShells(t *testing.T) {
+	Reset()
+	NewOptArg('Z', "zzfile", "file to zap")
+	expected := map[string]string{
+		"bash": "--zzfile\n",
+		"zsh": "--zzfile:file to zap\n",
+		"fish": "--zzfile\tfile to zap\n",
+	}
+	for shell, want := range expected {
+		var b bytes.Buffer
+		if !Complete(&b, []string{ CompleteCommand, "--shell=" + shell, "--", "--zz" }) {
+			t.Fatal("Completion request not recognized")
+		}
+		if b.String() != want {
+			t.Fatalf("Expected %q for %s, got %q", want, shell, b.String())
+		}
+	}
+}
+
+//Check that nothing is offered for an option's argument
+func TestCompleteValue(t *testing.T) {
+	Reset()
+	NewOptArg('Z', "zzfile", "file to zap")
+	var b bytes.Buffer
+	Complete(&b, []string{ CompleteCommand, "--shell=bash", "--", "--zzfile", "-" })
+	if b.Len() != 0 {
+		t.Fatalf("Expected no candidates, got %q", b.String())
+	}
+	Complete(&b, []string{ CompleteCommand, "--shell=bash", "--", "-Z", "" })
+	if b.Len() != 0 {
+		t.Fatalf("Expected no candidates, got %q", b.String())
+	}
+}
+
+//Check that a lone dash offers the short options
+func TestCompleteShort(t *testing.T) {
+	Reset()
+	NewOptArg('Z', "zzfile", "file to zap")
+	var b bytes.Buffer
+	Complete(&b, []string{ CompleteCommand, "--", "-" })
+	if !strings.Contains(b.String(), "-Z\n") || !strings.Contains(b.String(), "--zzfile\n") {
+		t.Fatalf("Expected -Z and --zzfile, got %q", b.String())
+	}
+}
+
+//Check that ordinary arguments are not taken as requests
+func TestCompleteNotRequest(t *testing.T) {
+	var b bytes.Buffer
+	if Complete(&b, []string{ "--zzfile", "x" }) {
+		t.Fatal("Ordinary arguments taken as a completion request")
+	}
+	if b.Len() != 0 {
+		t.Fatal("Output written for ordinary arguments")
+	}
+}
+
+//Check that each script calls back into the program
+func TestCompletionScripts(t *testing.T) {
+	ProgramName = "/usr/bin/my-prog"
+	defer func() { ProgramName = "" }()
+	gens := map[string]func(*bytes.Buffer){
+		"bash": func(b *bytes.Buffer) { GenBashCompletion(b) },
+		"zsh": func(b *bytes.Buffer) { GenZshCompletion(b) },
+		"fish": func(b *bytes.Buffer) { GenFishCompletion(b) },
+	}
+	for shell, gen := range gens {
+		var b bytes.Buffer
+		gen(&b)
+		call := "my-prog " + CompleteCommand + " --shell=" + shell
+		if !strings.Contains(b.String(), call) {
+			t.Fatalf("Expected %s script to contain %q, got %q", shell, call, b.String())
+		}
+	}
+}
+
+//Check the PowerShell script and the responder's output for it
+func TestPowerShellCompletion(t *testing.T) {
+	Reset()
+	ProgramName = "prog"
+	defer func() { ProgramName = "" }()
+	expected := `Register-ArgumentCompleter -Native -CommandName 'prog' -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+	$words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+	if ($wordToComplete -eq '') { $words += '' }
+	& 'prog' __complete --shell=powershell -- @words | ForEach-Object {
+		$word, $desc = $_ -split "` + "`" + `t", 2
+		if (-not $desc) { $desc = $word }
+		[System.Management.Automation.CompletionResult]::new($word, $word, 'ParameterName', $desc)
+	}
+}
+`
+	var b bytes.Buffer
+	GenPowerShellCompletion(&b)
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	NewOptArg('Z', "zzfile", "file to zap")
+	b.Reset()
+	Complete(&b, []string{ CompleteCommand, "--shell=powershell", "--", "--zz" })
+	if b.String() != "--zzfile\tfile to zap\n" {
+		t.Fatalf("Expected --zzfile with description, got %q", b.String())
+	}
+	b.Reset()
+	Complete(&b, []string{ CompleteCommand, "--shell=powershell", "--", "--zzfile", "--" })
+	if b.Len() != 0 {
+		t.Fatalf("Expected no candidates after --zzfile, got %q", b.String())
+	}
+}
+
+//Check that the bash script lists every option and the choices
+//for those that have them
+func TestBashCompletionOptions(t *testing.T) {
+	p := NewParser()
+	p.ProgramName = "prog"
+	p.NewOptArg('c', "color", "when to color").SetChoices("always", "never", "auto")
+	p.NewFlag('v', "verbose", "print more")
+	p.NewOptVec(0, "include", "directories to search")
+	var b bytes.Buffer
+	p.GenBashCompletion(&b)
+	script := b.String()
+	for _, word := range []string{ "--color", "-c", "--verbose", "-v", "--include", "'always never auto'", "--color|-c)" } {
+		if !strings.Contains(script, word) {
+			t.Fatalf("Expected %s in the script, got:\n%s", word, script)
+		}
+	}
+	b.Reset()
+	p.Complete(&b, []string{ CompleteCommand, "--", "--color", "a" })
+	if b.String() != "always\nauto\n" {
+		t.Fatalf("Expected the choices starting with a, got %q", b.String())
+	}
+}
+
+//Check that hidden options are not offered
+func TestCompleteHidden(t *testing.T) {
+	Reset()
+	NewOptArg('Z', "zzfile", "file to zap")
+	NewFlag('P', "zzprofile", "profile the zapping").Hidden = true
+	var b bytes.Buffer
+	Complete(&b, []string{ CompleteCommand, "--", "-" })
+	if !strings.Contains(b.String(), "--zzfile\n") || strings.Contains(b.String(), "-P\n") || strings.Contains(b.String(), "--zzprofile") {
+		t.Fatalf("Expected --zzfile without -P or --zzprofile, got %q", b.String())
+	}
+	b.Reset()
+	GenBashCompletion(&b)
+	if strings.Contains(b.String(), "zzprofile") || strings.Contains(b.String(), " -P") {
+		t.Fatalf("Expected no hidden option in the script, got %q", b.String())
+	}
+}
@@ -0,0 +1,82 @@
+package getopt
+
+import(
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+//If set, lines of a defaults file naming unknown options are
+//reported to ErrorWriter and skipped, rather than failing
+//LoadDefaults
+var IgnoreUnknownDefaults bool
+
+//Set option values from a file of "long = value" lines, to be
+//overridden by the arguments of a later parse.  Blank lines, lines
+//starting with '#' or ';' and "[section]" lines are skipped.  Values
+//are parsed as in "--long=value", and an OptVec given more than once
+//takes each value, replacing its current arguments.  Options set
+//from the file are not marked as seen, and OnSet is not called.  If
+//a line is in error, the options on the lines before it are still
+//set
+func LoadDefaults(path string) error {
+	return defaultParser.LoadDefaults(path)
+}
+
+//Set option values from a file of "long = value" lines, to be
+//overridden by the arguments of a later parse.  Blank lines, lines
+//starting with '#' or ';' and "[section]" lines are skipped.  Values
+//are parsed as in "--long=value", and an OptVec given more than once
+//takes each value, replacing its current arguments.  Options set
+//from the file are not marked as seen, and OnSet is not called.  If
+//a line is in error, the options on the lines before it are still
+//set
+func (p *Parser) LoadDefaults(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read defaults file %s:  %w", path, err)
+	}
+	defer file.Close()
+	//OptVecs set from the file, whose arguments are replaced by
+	//the first value and appended to by the rest
+	vecs := make(map[*OptVec]bool, initialCapacity)
+	//OptVecs still hold the file's values until they are passed
+	defer func() {
+		for v := range vecs {
+			v.defaulted = true
+		}
+	}()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || text[0] == '#' || text[0] == ';' || text[0] == '[' {
+			continue
+		}
+		equals := strings.IndexByte(text, '=')
+		if equals == -1 {
+			return fmt.Errorf("%s:%d:  Expected long = value, got %s", path, line, text)
+		}
+		long, value := strings.TrimSpace(text[:equals]), strings.TrimSpace(text[equals + 1:])
+		opt, ok := p.optByLong[long]
+		if !ok {
+			err := &UsageError{ &UnknownOptionError{ long, true } }
+			if IgnoreUnknownDefaults {
+				fmt.Fprintf(ErrorWriter, "%s:%d:  %s\n", path, line, err)
+				continue
+			}
+			return fmt.Errorf("%s:%d:  %w", path, line, err)
+		}
+		if v, vec := opt.(*OptVec); vec && !vecs[v] {
+			v.defaulted = true
+			vecs[v] = true
+		}
+		if err := setValue(opt, value); err != nil {
+			return fmt.Errorf("%s:%d:  %w", path, line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Unable to read defaults file %s:  %w", path, err)
+	}
+	return nil
+}
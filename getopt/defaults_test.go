@@ -0,0 +1,81 @@
+package getopt
+
+import(
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//Check that a defaults file sets options that the arguments override
+func TestLoadDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "defaults")
+	contents := "# comment\n[main]\nfile = in.txt\n\nport=8080\nverbose = true\ninclude = a\ninclude = b\n"
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewParser()
+	f := p.NewOptArg('f', "file", "file to read")
+	port := p.NewOptInt('p', "port", "port to listen on")
+	v := p.NewFlag('v', "verbose", "print more")
+	inc := p.NewOptVec('I', "include", "include directory").SetDefault([]string{ "x" })
+	if err := p.LoadDefaults(path); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "in.txt" || port.Value != 8080 || !v.Passed || fmt.Sprint(inc.OptArgs) != "[a b]" {
+		t.Fatalf("Expected the file's values, got '%s' %d %t %v", f.Opt, port.Value, v.Passed, inc.OptArgs)
+	}
+	if f.Seen || port.Seen || inc.Seen {
+		t.Fatal("Expected options from the file not to be seen")
+	}
+	if err := p.ParseArgv([]string{ "--port", "9090", "-I", "c" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "in.txt" || port.Value != 9090 || fmt.Sprint(inc.OptArgs) != "[c]" {
+		t.Fatalf("Expected the arguments to override the file, got '%s' %d %v", f.Opt, port.Value, inc.OptArgs)
+	}
+}
+
+//Check the errors for bad lines, and that unknown options can be
+//skipped
+func TestLoadDefaultsErrors(t *testing.T) {
+	dir := t.TempDir()
+	p := NewParser()
+	port := p.NewOptInt('p', "port", "port to listen on")
+	if err := p.LoadDefaults(filepath.Join(dir, "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected an error for the missing file, got %v", err)
+	}
+	cases := []struct {
+		contents	string
+		expected	string
+	}{
+		{ "port = http\n", ":1:  Invalid value http for --port, expected an integer" },
+		{ "port = 80\nbogus = 1\n", ":2:  Unrecognized long option bogus" },
+		{ "port 80\n", ":1:  Expected long = value, got port 80" },
+	}
+	path := filepath.Join(dir, "defaults")
+	for _, c := range cases {
+		if err := os.WriteFile(path, []byte(c.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.LoadDefaults(path); err == nil || err.Error() != path + c.expected {
+			t.Fatalf("Expected %s%s, got %v", path, c.expected, err)
+		}
+	}
+
+	var b bytes.Buffer
+	defer func(w io.Writer) { ErrorWriter, IgnoreUnknownDefaults = w, false }(ErrorWriter)
+	ErrorWriter, IgnoreUnknownDefaults = &b, true
+	if err := os.WriteFile(path, []byte("bogus = 1\nport = 81\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.LoadDefaults(path); err != nil || port.Value != 81 {
+		t.Fatalf("Expected the unknown option skipped, got %d, %v", port.Value, err)
+	}
+	if b.String() != path + ":1:  Unrecognized long option bogus\n" {
+		t.Fatalf("Expected a warning for the unknown option, got %q", b.String())
+	}
+}
@@ -0,0 +1,227 @@
+package getopt
+
+import(
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+//Returned when help was requested, after it has been printed
+var ErrHelpRequested = errors.New("Help requested")
+
+//Returned when the version was requested, after it has been printed
+var ErrVersionRequested = errors.New("Version requested")
+
+//An error in the arguments passed by the user, such as an
+//unrecognized option or a missing argument, as opposed to an
+//error returned by a handler or check
+type UsageError struct {
+	Err	error
+}
+
+func (e *UsageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UsageError) Unwrap() error {
+	return e.Err
+}
+
+//Returned by ParseArgv for an unknown or ambiguous option, or an
+//argument that is not valid for its option, giving where in argv
+//it was.  It wraps the UsageError describing the problem
+type ArgError struct {
+	//Index of the argument in argv
+	Index	int
+	//The argument as passed
+	Arg	string
+	Err	error
+}
+
+func (e *ArgError) Error() string {
+	return fmt.Sprintf("Argument %d (%q):  %s", e.Index, e.Arg, e.Err)
+}
+
+func (e *ArgError) Unwrap() error {
+	return e.Err
+}
+
+//Returned, wrapped in a UsageError, when required options were
+//not passed
+type MissingRequiredError struct {
+	//Names of the missing options, e.g., "-f/--file"
+	Options	[]string
+}
+
+func (e *MissingRequiredError) Error() string {
+	return "Missing required options:  " + strings.Join(e.Options, ", ")
+}
+
+//Returned, wrapped in a UsageError, for an option that is not
+//registered
+type UnknownOptionError struct {
+	//Name of the option as passed, without its prefix.  Empty for
+	//a long option with no name, as in "--=value"
+	Name	string
+	//Whether it was passed as a long option
+	Long	bool
+}
+
+func (e *UnknownOptionError) Error() string {
+	if e.Long && e.Name == "" {
+		return "Empty long option name"
+	} else if e.Long {
+		return "Unrecognized long option " + e.Name
+	}
+	return fmt.Sprintf("Unrecognized short option:  '%s'", e.Name)
+}
+
+//Returned, wrapped in a UsageError, for an abbreviated long option
+//that more than one option's name starts with
+type AmbiguousOptionError struct {
+	//Name of the option as passed
+	Name	string
+	//The long options it could be, e.g., "--verbose"
+	Candidates	[]string
+}
+
+func (e *AmbiguousOptionError) Error() string {
+	return fmt.Sprintf("Ambiguous long option %s:  %s", e.Name, strings.Join(e.Candidates, ", "))
+}
+
+//Returned, wrapped in a UsageError, when the arguments end with
+//an option that needs an argument
+type MissingArgError struct {
+	Short	byte
+	Long	string
+}
+
+func (e *MissingArgError) Error() string {
+	if e.Short == 0 {
+		return "Expecting argument for option:  " + longPrefix() + e.Long
+	}
+	return fmt.Sprintf("Expecting argument for option:  %c%c/%s%s", OptionPrefix, e.Short, longPrefix(), e.Long)
+}
+
+//Returned, wrapped in a UsageError, for a group of short options,
+//such as "-vq", when AllowClumping is not set
+type ClumpedShortsError struct {
+	//The argument as passed
+	Arg	string
+}
+
+func (e *ClumpedShortsError) Error() string {
+	return "Short options must be passed separately:  " + e.Arg
+}
+
+//Returned, wrapped in a UsageError, by Dispatch when no command
+//was given or the one given is not registered
+type UnknownCommandError struct {
+	//Name of the command as passed, or "" if there was none
+	Name	string
+	//The registered commands, sorted
+	Commands	[]string
+}
+
+func (e *UnknownCommandError) Error() string {
+	valid := strings.Join(e.Commands, ", ")
+	if e.Name == "" {
+		return "Missing command, expected one of:  " + valid
+	}
+	return fmt.Sprintf("Unknown command %s, expected one of:  %s", e.Name, valid)
+}
+
+//Returned, wrapped in a UsageError, when the argument of an option
+//is not valid for it, e.g., not a number or not one of its choices
+type ParseValueError struct {
+	//Long name of the option
+	Long	string
+	//The argument given
+	Value	string
+	//What was wrong with it
+	Err	error
+}
+
+func (e *ParseValueError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ParseValueError) Unwrap() error {
+	return e.Err
+}
+
+//Create a ParseValueError, wrapped in a UsageError, with a
+//formatted message
+func parseValueErrorf(long string, value string, format string, a ...any) error {
+	return &UsageError{ &ParseValueError{ long, value, fmt.Errorf(format, a...) } }
+}
+
+//Create a UsageError with a formatted message
+func usageErrorf(format string, a ...any) error {
+	return &UsageError{ fmt.Errorf(format, a...) }
+}
+
+//Called by HandleError to exit the program
+var exitFunc = os.Exit
+
+//Where HandleError and Parse print errors
+var ErrorWriter io.Writer = os.Stderr
+
+//If set, Parse prints any error, other than ErrHelpRequested and
+//ErrVersionRequested, and the usage synopsis to ErrorWriter before
+//returning it
+var PrintUsageOnError bool
+
+//Parse the program's arguments, os.Args[1:], as options.  If
+//PrintUsageOnError is set, errors are printed along with the usage
+//synopsis, so they need not be printed again.  The program is not
+//exited
+func Parse() error {
+	err := ParseArgv(os.Args[1:])
+	defaultParser.printError(err)
+	return err
+}
+
+//Parse the program's arguments, os.Args[1:], as options.  If
+//PrintUsageOnError is set, errors are printed along with the usage
+//synopsis, so they need not be printed again.  The program is not
+//exited
+func (p *Parser) Parse() error {
+	err := p.ParseArgv(os.Args[1:])
+	p.printError(err)
+	return err
+}
+
+//Print a parse error and the usage synopsis to ErrorWriter if
+//PrintUsageOnError is set
+func (p *Parser) printError(err error) {
+	if err == nil || !PrintUsageOnError || errors.Is(err, ErrHelpRequested) || errors.Is(err, ErrVersionRequested) {
+		return
+	}
+	fmt.Fprintln(ErrorWriter, err)
+	fmt.Fprintln(ErrorWriter, p.Usage())
+}
+
+//Exit the program appropriately for an error returned by parsing.
+//Does nothing if err is nil.  If help or the version was requested,
+//exits with status 0, since they have already been printed.  For a
+//UsageError, prints the error and the usage synopsis and exits
+//with status 2.  For any other error, prints it and exits with
+//status 1
+func HandleError(err error) {
+	switch {
+	case err == nil:
+		return
+	case errors.Is(err, ErrHelpRequested), errors.Is(err, ErrVersionRequested):
+		exitFunc(0)
+	case errors.As(err, new(*UsageError)):
+		fmt.Fprintln(ErrorWriter, err)
+		fmt.Fprintln(ErrorWriter, Usage())
+		exitFunc(2)
+	default:
+		fmt.Fprintln(ErrorWriter, err)
+		exitFunc(1)
+	}
+}
@@ -0,0 +1,255 @@
+package getopt
+
+import(
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+//Check the exit code chosen for each kind of error
+func TestHandleError(t *testing.T) {
+	Reset()
+	var b bytes.Buffer
+	code := -1
+	saveExit, saveOutput := exitFunc, ErrorWriter
+	exitFunc = func(c int) { code = c }
+	ErrorWriter = &b
+	defer func() { exitFunc, ErrorWriter = saveExit, saveOutput }()
+	NewFlag('f', "force", "force action")
+	usageErr := ParseArgv([]string{ "--bogus" })
+	cases := []struct {
+		err	error
+		code	int
+	}{
+		{ nil, -1 },
+		{ ErrHelpRequested, 0 },
+		{ fmt.Errorf("wrapped: %w", ErrVersionRequested), 0 },
+		{ usageErr, 2 },
+		{ errors.New("could not read input"), 1 },
+	}
+	for _, c := range cases {
+		code = -1
+		b.Reset()
+		HandleError(c.err)
+		if code != c.code {
+			t.Fatalf("Expected exit code %d for %v, got %d", c.code, c.err, code)
+		}
+	}
+	b.Reset()
+	HandleError(usageErr)
+	if !bytes.Contains(b.Bytes(), []byte("usage:")) {
+		t.Fatalf("Expected usage to be printed, got %q", b.String())
+	}
+}
+
+//Check that each kind of error can be extracted with errors.As
+func TestTypedErrors(t *testing.T) {
+	AllowAbbrev = true
+	defer func() { AllowAbbrev = false }()
+	p := NewParser()
+	p.NewFlag('v', "verbose", "print more")
+	p.NewFlag('V', "version", "print the version")
+	p.NewOptArg('f', "file", "file to read")
+	p.NewOptInt('p', "port", "port to listen on")
+
+	var unknown *UnknownOptionError
+	if err := p.ParseArgv([]string{ "--bogus=1" }); !errors.As(err, &unknown) || unknown.Name != "bogus" || !unknown.Long {
+		t.Fatalf("Expected unknown --bogus, got %v", err)
+	}
+	if err := p.ParseArgv([]string{ "-vx" }); !errors.As(err, &unknown) || unknown.Name != "x" || unknown.Long {
+		t.Fatalf("Expected unknown -x, got %v", err)
+	}
+	if err := p.ParseMap(map[string]string{ "bogus": "1" }); !errors.As(err, &unknown) || unknown.Name != "bogus" {
+		t.Fatalf("Expected unknown bogus from ParseMap, got %v", err)
+	}
+
+	var ambiguous *AmbiguousOptionError
+	if err := p.ParseArgv([]string{ "--ver" }); !errors.As(err, &ambiguous) || len(ambiguous.Candidates) != 2 {
+		t.Fatalf("Expected ambiguous --ver, got %v", err)
+	}
+
+	var missing *MissingArgError
+	if err := p.ParseArgv([]string{ "-f" }); !errors.As(err, &missing) || missing.Long != "file" {
+		t.Fatalf("Expected missing argument for --file, got %v", err)
+	}
+	if err := p.ParseArgv([]string{ "--port" }); !errors.As(err, &missing) || missing.Short != 'p' {
+		t.Fatalf("Expected missing argument for -p, got %v", err)
+	}
+
+	var value *ParseValueError
+	if err := p.ParseArgv([]string{ "--port=http" }); !errors.As(err, &value) || value.Long != "port" || value.Value != "http" {
+		t.Fatalf("Expected bad value for --port, got %v", err)
+	}
+	err := p.ParseArgv([]string{ "--verbose=maybe" })
+	if !errors.As(err, &value) || value.Long != "verbose" {
+		t.Fatalf("Expected bad value for --verbose, got %v", err)
+	}
+	if err.Error() != `Argument 0 ("--verbose=maybe"):  Invalid value maybe for --verbose, expected a boolean` {
+		t.Fatalf("Expected the message to name the option and value, got %s", err)
+	}
+	if !errors.As(err, new(*UsageError)) {
+		t.Fatal("Expected the error to be a UsageError")
+	}
+}
+
+//Check that every recoverable error is reported when collecting
+func TestCollectErrors(t *testing.T) {
+	CollectErrors = true
+	defer func() { CollectErrors = false }()
+	p := NewParser()
+	q := p.NewFlag('q', "quiet", "print less")
+	f := p.NewOptArg('f', "file", "file to read")
+	port := p.NewOptInt('p', "port", "port to listen on")
+	p.NewOptInt('n', "count", "how many")
+	argv := []string{ "--bogus", "-xq", "--port=http", "-f", "a", "--verbose=1", "-n" }
+	err := p.ParseArgv(argv)
+	if err == nil {
+		t.Fatal("Expected errors")
+	}
+	var unknown *UnknownOptionError
+	var value *ParseValueError
+	var missing *MissingArgError
+	if !errors.As(err, &unknown) || !errors.As(err, &value) || !errors.As(err, &missing) {
+		t.Fatalf("Expected unknown, bad value and missing argument errors, got %v", err)
+	}
+	expected := `Argument 0 ("--bogus"):  Unrecognized long option bogus` + "\n" +
+		`Argument 1 ("-xq"):  Unrecognized short option:  'x'` + "\n" +
+		`Argument 2 ("--port=http"):  Invalid value http for --port, expected an integer` + "\n" +
+		`Argument 5 ("--verbose=1"):  Unrecognized long option verbose` + "\n" +
+		"Expecting argument for option:  -n/--count"
+	if err.Error() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, err)
+	}
+	if !q.Passed || f.Opt != "a" || port.Value != 0 {
+		t.Fatalf("Expected the valid options set, got %t '%s' %d", q.Passed, f.Opt, port.Value)
+	}
+
+	CollectErrors = false
+	if err := p.ParseArgv(argv); !errors.As(err, &unknown) || unknown.Error() != "Unrecognized long option bogus" {
+		t.Fatalf("Expected only the first error without CollectErrors, got %v", err)
+	}
+}
+
+//Check that Parse prints errors and the synopsis only when asked
+func TestPrintUsageOnError(t *testing.T) {
+	var b bytes.Buffer
+	defer func(w io.Writer, args []string) { ErrorWriter, os.Args = w, args }(ErrorWriter, os.Args)
+	ErrorWriter = &b
+	os.Args = []string{ "prog", "--bogus" }
+	p := NewParser()
+	p.ProgramName = "prog"
+	p.NewFlag('f', "force", "force action")
+	if err := p.Parse(); err == nil || b.Len() != 0 {
+		t.Fatalf("Expected an error and no output by default, got %v %q", err, b.String())
+	}
+	PrintUsageOnError = true
+	defer func() { PrintUsageOnError = false }()
+	if err := p.Parse(); err == nil {
+		t.Fatal("Expected an error for --bogus")
+	}
+	expected := "Argument 0 (\"--bogus\"):  Unrecognized long option bogus\nusage: prog [-f] [args...]\n"
+	if b.String() != expected {
+		t.Fatalf("Expected %q, got %q", expected, b.String())
+	}
+	b.Reset()
+	os.Args = []string{ "prog", "-f", "x" }
+	if err := p.Parse(); err != nil || b.Len() != 0 || fmt.Sprint(p.Rest) != "[x]" {
+		t.Fatalf("Expected a quiet successful parse, got %v %q %v", err, b.String(), p.Rest)
+	}
+}
+
+//Check that errors for arguments give where the argument was
+func TestArgErrorPosition(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('x', "extra", "extra output")
+	p.NewOptInt('p', "port", "port to listen on")
+	cases := []struct {
+		argv	[]string
+		index	int
+		arg	string
+	}{
+		{ []string{ "-x", "a", "-xy" }, 2, "-xy" },
+		{ []string{ "-x", "--bogus" }, 1, "--bogus" },
+		{ []string{ "a", "--port", "http" }, 2, "http" },
+		{ []string{ "-xp", "80", "b", "-p0x" }, 3, "-p0x" },
+	}
+	for _, c := range cases {
+		err := p.ParseArgv(c.argv)
+		var argErr *ArgError
+		if !errors.As(err, &argErr) || argErr.Index != c.index || argErr.Arg != c.arg {
+			t.Fatalf("Expected argument %d %s for %v, got %v", c.index, c.arg, c.argv, err)
+		}
+		prefix := fmt.Sprintf("Argument %d (%q):  ", c.index, c.arg)
+		if !strings.HasPrefix(err.Error(), prefix) || !errors.As(err, new(*UsageError)) {
+			t.Fatalf("Expected a usage error starting with %q, got %v", prefix, err)
+		}
+	}
+}
+
+//Check that a value of the wrong kind names the option and the value
+func TestValueErrorMessages(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('f', "force", "force action")
+	p.NewOptCount('v', "verbose", "print more")
+	p.NewOptInt('p', "port", "port to listen on")
+	p.NewOptFloat('r', "rate", "requests per second")
+	p.NewOptDuration('t', "timeout", "how long to wait")
+	p.NewOptArg('F', "format", "output format").SetChoices("json", "yaml")
+	p.NewOptArg('n', "name", "name to use").Validate = func(string) error { return errors.New("too short") }
+	cases := []struct {
+		argv	[]string
+		message	string
+	}{
+		{ []string{ "--force=oops" }, "Invalid value oops for --force, expected a boolean" },
+		{ []string{ "--verbose=abc" }, "Invalid value abc for --verbose, expected an integer" },
+		{ []string{ "--port=http" }, "Invalid value http for --port, expected an integer" },
+		{ []string{ "--rate=fast" }, "Invalid value fast for --rate, expected a number" },
+		{ []string{ "--timeout=soon" }, "Invalid value soon for --timeout, expected a duration" },
+		{ []string{ "--format=toml" }, "Invalid value toml for --format, expected one of:  json, yaml" },
+		{ []string{ "-n", "x" }, "Invalid value x for --name:  too short" },
+	}
+	for _, c := range cases {
+		err := p.ParseArgv(c.argv)
+		var value *ParseValueError
+		if !errors.As(err, &value) || value.Error() != c.message {
+			t.Fatalf("Expected %q for %v, got %v", c.message, c.argv, err)
+		}
+	}
+	AllowFlagValues = false
+	defer func() { AllowFlagValues = true }()
+	err := p.ParseArgv([]string{ "--force=true" })
+	var value *ParseValueError
+	if !errors.As(err, &value) || value.Error() != "Option --force takes no value, got true" {
+		t.Fatalf("Expected --force to take no value, got %v", err)
+	}
+	if err := p.ParseArgv([]string{ "--force", "+f" }); err != nil {
+		t.Fatalf("Expected the flag without a value to be allowed, got %v", err)
+	}
+}
+
+//Check that an unknown short option passed alone is reported like
+//one in a group
+func TestUnknownShortAlone(t *testing.T) {
+	p := NewParser()
+	q := p.NewFlag('q', "quiet", "print less")
+	for _, argv := range [][]string{ { "-q", "-x" }, { "-q", "+x" } } {
+		err := p.ParseArgv(argv)
+		var unknown *UnknownOptionError
+		var argErr *ArgError
+		if !errors.As(err, &unknown) || unknown.Name != "x" || unknown.Long || !errors.As(err, &argErr) || argErr.Index != 1 {
+			t.Fatalf("Expected unknown -x at argument 1 for %v, got %v", argv, err)
+		}
+	}
+	CollectErrors = true
+	defer func() { CollectErrors = false }()
+	err := p.ParseArgv([]string{ "-x", "+y", "-q" })
+	expected := `Argument 0 ("-x"):  Unrecognized short option:  'x'` + "\n" +
+		`Argument 1 ("+y"):  Unrecognized short option:  'y'`
+	if err == nil || err.Error() != expected || !q.Passed {
+		t.Fatalf("Expected both errors collected and -q set, got %t, %v", q.Passed, err)
+	}
+}
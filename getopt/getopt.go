@@ -3,13 +3,14 @@
 //
 //This package provides simple long and short option parsing.
 //Short, i.e. single byte, options are recognized by being
-//proceeded by '-', long options by "--".  It provides the
-//
+//proceeded by '-', long options by "--".  Both can be changed
+//by setting OptionPrefix and NegationPrefix.  It provides the
 //following option types:
 //Flag:  Either true or false.  Set to true if passed.  Passing
 //the short flag with '+' will set the flag to false, as will
-//setting the long option equal to false.  E.g., the arguments
-//"+f" and "--force=False" will both set the flag to false.
+//setting the long option equal to false, or prefixing it with
+//"no-".  E.g., the arguments "+f", "--no-force" and
+//"--force=False" will all set the flag to false.
 //"-f" and "--force" and "--force=True" will all set the flag
 //to true.  This is to facilitate shell scripting, since
 //options can be set by default and overridden
@@ -17,13 +18,22 @@
 //OptArg:  Takes a single argument.  Can be set like
 //--file=some_file.txt or --file some_file.txt using long
 //options, or -fsome_file.txt or -f some_file.txt all set
-//that option to some_file.txt.  Subsequent occurrences of
-//the option will overwrite the previous value
+//that option to some_file.txt, as does -f=some_file.txt.
+//Subsequent occurrences of the option will overwrite the
+//previous value.  If its
+//argument is optional, only --file=some_file.txt and
+//-fsome_file.txt set it, and --file or -f set it to its
+//implicit value.  It holds its default until passed, and
+//"+f" restores the default
 //
 //OptVec:  Takes one or more arguments.  Can be set like
 //OptArg, except that multiple occurrences will append
 //to the array of arguments for the option.  Can be used
-//for, e.g., processing multiple files
+//for, e.g., processing multiple files.  Its default arguments
+//are replaced, not appended to, and "+f" restores them.  A
+//greedy OptVec takes every argument up to the next option, so
+//"--cmd a b -- c d" gives it "a" and "b".  The "--" ends
+//it, and, as always, the options, so "c" and "d" go to Rest
 //
 //OptCount:  Returns the number of times it has been passed,
 //or a number passed directly.  For example:
@@ -31,49 +41,347 @@
 //the same thing.  If the short option is negated, "+v" then
 //the value is subtracted instead of incremented
 //
+//OptInt:  Takes a single integer argument, set like OptArg.
+//E.g., "--port=8080", "--port 0x1f90" and "-p8080".  An argument
+//that is not a number is an error
+//
+//OptFloat:  Like OptInt, but takes a floating point argument,
+//e.g., "--rate=0.25" or "-r 0.25"
+//
+//OptDuration:  Like OptInt, but takes a duration, e.g.,
+//"--timeout=30s", "--timeout 1m30s" or "-t500ms"
+//
 //Options should be created with their respective constructors, since
 //this stores the option in the maps and lists, which is used for
-//parsing
+//parsing.  Creating an option with a short or long name already
+//taken panics
 //
 //Use ParseArgv to parse a supplies argument vector, and GetOpts to parse
-//os.Args
+//os.Args.  An argument such as "@args.txt" is replaced by the words
+//in that file, unless ArgFilePrefix is 0.  Every argument after
+//"--" goes to Rest as it is, even "-" and those that look like
+//options
+//
+//The package level functions all work on a default Parser.  Create
+//more with NewParser to keep independent sets of options
 package getopt
 
 import(
 	"errors"
 	"strings"
 	"fmt"
+	"io"
 	"strconv"
 	"os"
+	"sort"
+	"time"
+	"unicode/utf8"
 )
 
-//Print program name, description, version and help
+//If set, PrintHelp lists the options that take no argument
+//together on a few lines, e.g., "-a -b -c  --quiet", before
+//the options that take one
+var CompactFlags bool
+
+//Width the help is wrapped to if neither HelpWidth nor COLUMNS
+//gives one
+const defaultHelpWidth = 80
+
+//Least width left for help strings when wrapping them.  With less
+//room than this beside the names they are not wrapped
+const minHelpWrap = 20
+
+//Width the help is wrapped to.  If 0, the COLUMNS environment
+//variable gives it, or, if that is unset, 80
+var HelpWidth int
+
+//Return the width the help is wrapped to
+func helpWidth() int {
+	if HelpWidth > 0 {
+		return HelpWidth
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultHelpWidth
+}
+
+//Break text into lines of at most width runes at spaces.  A word
+//wider than that has a line to itself
+func wrapWords(text string, width int) []string {
+	lines := make([]string, 0, initialCapacity)
+	line := ""
+	for _, word := range strings.Fields(text) {
+		if line == "" {
+			line = word
+		} else if utf8.RuneCountInString(line) + 1 + utf8.RuneCountInString(word) > width {
+			lines = append(lines, line)
+			line = word
+		} else {
+			line += " " + word
+		}
+	}
+	return append(lines, line)
+}
+
+//Where PrintHelp writes the help, and PrintVersion the version
+var HelpWriter io.Writer = os.Stdout
+
+//Print program name, description, version and help to HelpWriter
 func PrintHelp() {
-	fmt.Printf("%s - %s\n", ProgramName, ProgramVersion)
-	fmt.Println(ProgramDesc)
-	f := "-%c/--%-32s\t%s\n"
-	for _, opt := range optByLong {
-		switch opt.(type) {
-		case *Flag:
-			fmt.Printf(f, opt.(*Flag).Short, opt.(*Flag).Long, opt.(*Flag).Help)
-		case *OptArg:
-			fmt.Printf(f, opt.(*OptArg).Short, opt.(*OptArg).Long, opt.(*OptArg).Help)
-		case *OptVec:
-			fmt.Printf(f, opt.(*OptVec).Short, opt.(*OptVec).Long, opt.(*OptVec).Help)
-		case *OptCount:
-			fmt.Printf(f, opt.(*OptCount).Short, opt.(*OptCount).Long, opt.(*OptCount).Help)
-		default:
-			panic("Unexpected type in array of Opt by long")
+	loadDefault()
+	defaultParser.PrintHelp()
+}
+
+//Print program name, description, version and help to HelpWriter
+func (p *Parser) PrintHelp() {
+	io.WriteString(HelpWriter, p.HelpString())
+}
+
+//Return the help PrintHelp prints
+func HelpString() string {
+	loadDefault()
+	return defaultParser.HelpString()
+}
+
+//Return the help PrintHelp prints
+func (p *Parser) HelpString() string {
+	var b strings.Builder
+	p.writeHelp(&b)
+	return b.String()
+}
+
+//Return the names of an option as given in the help, e.g.,
+//"-f/--file", or "   --file" if it has no short name, so that
+//the long names line up.  Aliases follow, as in "-c/--color,
+//--colour"
+func (p *Parser) helpName(opt Option) string {
+	name := "   " + longPrefix() + opt.GetLong()
+	if short := p.shortName(opt); short != "" {
+		name = string(OptionPrefix) + short + "/" + longPrefix() + opt.GetLong()
+	}
+	for _, alias := range p.aliases[opt] {
+		name += ", " + longPrefix() + alias
+	}
+	return name
+}
+
+//Write program name, version, description, if any, and help to w,
+//with options in order of long name.  Options with a Category
+//are listed after the others, under a heading for each category.
+//The help strings are aligned on the longest name, and wrapped to
+//the width given by helpWidth
+func (p *Parser) writeHelp(w io.Writer) {
+	fmt.Fprintf(w, "%s - %s\n", p.ProgramName, p.ProgramVersion)
+	if p.ProgramDesc != "" {
+		fmt.Fprintln(w, p.ProgramDesc)
+	}
+	longs := p.visibleLongs()
+	if CompactFlags {
+		shorts := make([]string, 0, initialCapacity)
+		longOnly := make([]string, 0, initialCapacity)
+		for _, long := range longs {
+			opt := p.optByLong[long]
+			if hasArg(opt) != NoArgument {
+				continue
+			}
+			if short := p.shortName(opt); short != "" {
+				shorts = append(shorts, string(OptionPrefix) + short)
+			} else {
+				longOnly = append(longOnly, longPrefix() + long)
+			}
+		}
+		line := ""
+		for i, tok := range append(shorts, longOnly...) {
+			sep := " "
+			if i == len(shorts) && i > 0 {
+				sep = "  "
+			}
+			if line == "" {
+				line = tok
+			} else if utf8.RuneCountInString(line + sep + tok) > helpWidth() {
+				fmt.Fprintln(w, line)
+				line = tok
+			} else {
+				line += sep + tok
+			}
+		}
+		if line != "" {
+			fmt.Fprintln(w, line)
+		}
+	}
+	listed := make([]Option, 0, len(longs))
+	width := 0
+	for _, long := range longs {
+		opt := p.optByLong[long]
+		if CompactFlags && hasArg(opt) == NoArgument {
+			continue
+		}
+		listed = append(listed, opt)
+		if utf8.RuneCountInString(p.helpName(opt)) > width {
+			width = utf8.RuneCountInString(p.helpName(opt))
+		}
+	}
+	//Uncategorized options come first, then each category under
+	//its heading, in the order the categories were first used
+	categories := []string{ "" }
+	used := map[string]bool{ "": true }
+	for _, opt := range p.opts {
+		if !used[opt.GetCategory()] {
+			used[opt.GetCategory()] = true
+			categories = append(categories, opt.GetCategory())
+		}
+	}
+	for _, category := range categories {
+		heading := category != ""
+		for _, opt := range listed {
+			if opt.GetCategory() != category {
+				continue
+			}
+			if heading {
+				fmt.Fprintf(w, "\n%s:\n", category)
+				heading = false
+			}
+			help := opt.GetHelp()
+			if room := helpWidth() - width - 2; utf8.RuneCountInString(help) > room && room >= minHelpWrap {
+				//Continuation lines are indented to the help
+				help = strings.Join(wrapWords(help, room), "\n" + strings.Repeat(" ", width + 2))
+			}
+			fmt.Fprintf(w, "%-*s  %s\n", width, p.helpName(opt), help)
 		}
 	}
 }
 
-//Print program name and version
+//Print program name and version to HelpWriter
 func PrintVersion() {
-	fmt.Printf("%s - %s\n", ProgramName, ProgramVersion)
-	panic("Not implemented")
+	loadDefault()
+	defaultParser.PrintVersion()
+}
+
+//Print program name and version to HelpWriter
+func (p *Parser) PrintVersion() {
+	fmt.Fprintf(HelpWriter, "%s - %s\n", p.ProgramName, p.ProgramVersion)
+}
+
+//Register -h/--help and -V/--version.  When either is passed,
+//ParseArgv prints the help or version and returns ErrHelpRequested
+//or ErrVersionRequested, leaving it to the caller to exit
+func EnableHelpVersion() {
+	defaultParser.EnableHelpVersion()
+}
+
+//Register -h/--help and -V/--version.  When either is passed,
+//ParseArgv prints the help or version and returns ErrHelpRequested
+//or ErrVersionRequested, leaving it to the caller to exit
+func (p *Parser) EnableHelpVersion() {
+	p.helpFlag = p.NewFlag('h', "help", "Print this help and exit")
+	p.versionFlag = p.NewFlag('V', "version", "Print the version and exit")
+}
+
+//Whether the flag was passed in the last parse.  It also counts
+//if it was taken as the argument of an option, e.g., "--file
+//--help", so that help is given even when the command line is
+//incomplete
+func (p *Parser) requested(f *Flag, argv []string) bool {
+	if f == nil {
+		return false
+	}
+	if p.seenOpts[f] {
+		return true
+	}
+	for _, arg := range argv {
+		if arg == longPrefix() {
+			break
+		}
+		if arg == longPrefix() + f.Long || (f.Short != 0 && arg == string([]byte{ OptionPrefix, f.Short })) {
+			return true
+		}
+	}
+	return false
+}
+
+//Return the synopsis of an option, e.g., "-f FILE", preferring
+//the short name
+func (p *Parser) usageToken(opt Option) string {
+	name := longPrefix() + optLong(opt)
+	if short := p.shortName(opt); short != "" {
+		name = string(OptionPrefix) + short
+	}
+	metavar := strings.ToUpper(optLong(opt))
+	if o, ok := opt.(*OptArg); ok && o.ArgOptional {
+		if p.shortName(opt) != "" {
+			return name + "[" + metavar + "]"
+		}
+		return name + "[=" + metavar + "]"
+	}
+	if takesArg(opt) {
+		return name + " " + metavar
+	}
+	return name
+}
+
+//Return a one line synopsis of the program's usage, e.g.,
+//"usage: prog [-a] [-f FILE] [args...]".  Options are given
+//in order of long name, followed by the exclusive groups.
+//Required groups are given as "(-a | -b)", others as "[-a | -b]".
+//Hidden options are left out
+func Usage() string {
+	loadDefault()
+	return defaultParser.Usage()
+}
+
+//Return a one line synopsis of the program's usage, e.g.,
+//"usage: prog [-a] [-f FILE] [args...]".  Options are given
+//in order of long name, followed by the exclusive groups.
+//Required groups are given as "(-a | -b)", others as "[-a | -b]".
+//Hidden options are left out
+func (p *Parser) Usage() string {
+	grouped := make(map[Option]bool, initialCapacity)
+	for _, g := range p.exclusiveGroups {
+		for _, opt := range g.Opts {
+			grouped[opt] = true
+		}
+	}
+	longs := p.visibleLongs()
+	tokens := []string{ "usage:", p.completionName() }
+	for _, long := range longs {
+		if opt := p.optByLong[long]; grouped[opt] {
+			continue
+		} else if isRequired(opt) {
+			tokens = append(tokens, p.usageToken(opt))
+		} else {
+			tokens = append(tokens, "[" + p.usageToken(opt) + "]")
+		}
+	}
+	for _, g := range p.exclusiveGroups {
+		alts := make([]string, 0, len(g.Opts))
+		for _, opt := range g.Opts {
+			if !optHidden(opt) {
+				alts = append(alts, p.usageToken(opt))
+			}
+		}
+		if len(alts) == 0 {
+			continue
+		} else if g.Required {
+			tokens = append(tokens, "(" + strings.Join(alts, " | ") + ")")
+		} else {
+			tokens = append(tokens, "[" + strings.Join(alts, " | ") + "]")
+		}
+	}
+	tokens = append(tokens, "[args...]")
+	return strings.Join(tokens, " ")
 }
 
+//Print the synopsis given by Usage to HelpWriter
+func PrintUsage() {
+	loadDefault()
+	defaultParser.PrintUsage()
+}
+
+//Print the synopsis given by Usage to HelpWriter
+func (p *Parser) PrintUsage() {
+	fmt.Fprintln(HelpWriter, p.Usage())
+}
 
 // A flag is either true or false.  Can be negated with +b for short form,
 // and --flag=false, or --flag=F for long form.  This is to facilitate
@@ -83,22 +391,41 @@ type Flag struct {
 	Long	string
 	//Help string
 	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
 	//Short option
 	Short	byte
 	//Whether flag was passed
 	Passed	bool
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
 }
 
 //Create a new command flag
 func NewFlag(short byte, long string, help string) *Flag {
+	return defaultParser.NewFlag(short, long, help)
+}
+
+//Create a new command flag
+func (p *Parser) NewFlag(short byte, long string, help string) *Flag {
 	f := Flag{
 		Long:	long,
 		Short:	short,
 		Help:	help,
+		parser:	p,
 	}
-	flags = append(flags, f)
-	optByShort[short] = &f
-	optByLong[long] = &f
+	p.register(&f)
 	return &f
 }
 
@@ -109,23 +436,141 @@ func NewFlag(short byte, long string, help string) *Flag {
 type OptArg struct {
 	Long	string
 	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
 	Short	byte
 	Opt	string
+	//Set if the option was passed, in any form, in the last parse
+	Seen	bool
+	//If set, the argument may only be attached, as in --foo=bar
+	//or -fbar.  When the option is passed without one, Opt is
+	//set to Implicit, and the following argument is left alone
+	ArgOptional	bool
+	//Value used when an ArgOptional option has no argument
+	Implicit	string
+	//Value Opt holds until the option is passed, and is restored
+	//to when the option is negated
+	Default	string
+	//If not empty, the only values the argument may take
+	Choices	[]string
+	//Called with the argument once it is set.  An error from it
+	//ends the parse
+	Validate	func(string) error
+	//If set, parsing fails unless the option is passed
+	Required	bool
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
 }
 
 //Create a new OptArg
 func NewOptArg(short byte, long string, help string) *OptArg {
+	return defaultParser.NewOptArg(short, long, help)
+}
+
+//Create a new OptArg
+func (p *Parser) NewOptArg(short byte, long string, help string) *OptArg {
 	o := OptArg{
 		Long:	long,
 		Short:	short,
 		Help:	help,
+		parser:	p,
 	}
-	optArgs = append(optArgs, o)
-	optByShort[short] = &o
-	optByLong[long] = &o
+	p.register(&o)
 	return &o
 }
 
+//Create a new OptArg holding def until the option is passed
+func NewOptArgDefault(short byte, long string, help string, def string) *OptArg {
+	return defaultParser.NewOptArgDefault(short, long, help, def)
+}
+
+//Create a new OptArg holding def until the option is passed
+func (p *Parser) NewOptArgDefault(short byte, long string, help string, def string) *OptArg {
+	return p.NewOptArg(short, long, help).SetDefault(def)
+}
+
+//Create a new OptArg whose argument may be left out, in which
+//case it is set to implicit.  The argument must then be attached,
+//as in --long=value or -svalue
+func NewOptArgOptional(short byte, long string, help string, implicit string) *OptArg {
+	return defaultParser.NewOptArgOptional(short, long, help, implicit)
+}
+
+//Create a new OptArg whose argument may be left out, in which
+//case it is set to implicit.  The argument must then be attached,
+//as in --long=value or -svalue
+func (p *Parser) NewOptArgOptional(short byte, long string, help string, implicit string) *OptArg {
+	o := p.NewOptArg(short, long, help)
+	o.ArgOptional = true
+	o.Implicit = implicit
+	return o
+}
+
+//Set whether the option must be passed
+func (o *OptArg) SetRequired(required bool) *OptArg {
+	o.Required = required
+	return o
+}
+
+//Set the default value, and the current value along with it
+func (o *OptArg) SetDefault(def string) *OptArg {
+	o.Default = def
+	o.Opt = def
+	return o
+}
+
+//Restrict the argument to the values given
+func (o *OptArg) SetChoices(choices ...string) *OptArg {
+	o.Choices = choices
+	return o
+}
+
+//Set the argument, if it is one of the choices, and validate it
+func (o *OptArg) set(value string) error {
+	if err := o.checkChoice(value); err != nil {
+		return err
+	}
+	o.Opt = value
+	return validate(o.Validate, o.Long, value)
+}
+
+//Return an error unless the argument is one of the choices, or
+//there are none
+func (o *OptArg) checkChoice(value string) error {
+	if len(o.Choices) == 0 {
+		return nil
+	}
+	for _, c := range o.Choices {
+		if value == c {
+			return nil
+		}
+	}
+	return parseValueErrorf(o.Long, value, "Invalid value %s for %s%s, expected one of:  %s", value, longPrefix(), o.Long, strings.Join(o.Choices, ", "))
+}
+
+//Call an option's validation function, if it has one, naming the
+//option in the error
+func validate(f func(string) error, long string, value string) error {
+	if f == nil {
+		return nil
+	}
+	if err := f(value); err != nil {
+		return parseValueErrorf(long, value, "Invalid value %s for %s%s:  %w", value, longPrefix(), long, err)
+	}
+	return nil
+}
+
 //Creates a command argument that can hold an array of arguments.  Each
 //time the option appears, its argument is appended.
 //E.g., --foo=bar --foo=baz results in "foo" having an array holding
@@ -133,77 +578,358 @@ func NewOptArg(short byte, long string, help string) *OptArg {
 type OptVec struct {
 	Long	string
 	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
 	Short	byte
 	OptArgs	[]string
+	//Set if the option was passed, in any form, in the last parse
+	Seen	bool
+	//If set, every argument following the option is appended,
+	//up to the next option or "--".  "--" straight after the
+	//option ends it too, and the option is missing its argument
+	Greedy	bool
+	//Arguments OptArgs holds until the option is first passed,
+	//and is given a copy of again when the option is negated
+	Default	[]string
+	//Called with each argument once it is appended.  An error from
+	//it ends the parse
+	Validate	func(string) error
+	//If set, each argument is split on it, and the pieces appended.
+	//Empty pieces are kept, so "a,,b" gives "a", "" and "b", and
+	//"a," gives "a" and ""
+	Separator	string
+	//Least and most arguments allowed after parsing.  Zero means
+	//no limit
+	MinCount	int
+	MaxCount	int
+	//If set, parsing fails unless the option is passed
+	Required	bool
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
+	//Whether OptArgs still holds a copy of Default
+	defaulted	bool
 }
 
 //Construct a new OptVec
 func NewOptVec(short byte, long string, help string) *OptVec {
+	return defaultParser.NewOptVec(short, long, help)
+}
+
+//Construct a new OptVec
+func (p *Parser) NewOptVec(short byte, long string, help string) *OptVec {
 	v := OptVec{
 		Long:	long,
 		Short:	short,
 		Help:	help,
+		parser:	p,
 	}
-	optVecs = append(optVecs, v)
-	optByShort[short] = &v
-	optByLong[long] = &v
+	p.register(&v)
 	return &v
 }
 
+//Set whether the option must be passed
+func (v *OptVec) SetRequired(required bool) *OptVec {
+	v.Required = required
+	return v
+}
+
+//Set the default arguments, and the current arguments along with
+//them.  The first time the option is passed, the defaults are
+//replaced rather than appended to
+func (v *OptVec) SetDefault(def []string) *OptVec {
+	v.Default = def
+	v.OptArgs = append([]string(nil), def...)
+	v.defaulted = true
+	return v
+}
+
+//Restore a copy of the default arguments, replaced the next time
+//the option is passed
+func (v *OptVec) restore() {
+	v.OptArgs = append(make([]string, 0, len(v.Default)), v.Default...)
+	v.defaulted = true
+}
+
+//Append an argument, or its pieces if there is a separator,
+//dropping the defaults if they are still held, and validate it
+func (v *OptVec) add(arg string) error {
+	if v.defaulted {
+		v.OptArgs = nil
+		v.defaulted = false
+	}
+	pieces := []string{ arg }
+	if v.Separator != "" {
+		pieces = strings.Split(arg, v.Separator)
+	}
+	for _, piece := range pieces {
+		v.OptArgs = append(v.OptArgs, piece)
+		if err := validate(v.Validate, v.Long, piece); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 //An OptCount is like a flag, but holds the number of times it
 //has been passed, minus the number of times it has been negated.
 //You can also set the value directly.
 //For example, where 'v' is the short option, "verbose" the long,
-//"-vvv", "--verbose --verbose --verbose", and "--verbose=3" will
-//all set that option to have a value of three.  The number is
+//"-vvv", "--verbose --verbose --verbose", "--verbose=3" and "-v3"
+//will all set that option to have a value of three.  The number is
 //parsed with strconv.ParseInt with a base of 0, so binary, octal
-//and hexadecimal numbers are parsed as well
+//and hexadecimal numbers are parsed as well.
+//Arguments are applied in order:  setting the value replaces the
+//count so far, and passing or negating the option after that counts
+//on from it, so "--verbose=3 --verbose" gives four, and "--verbose
+//--verbose=3" gives three
 //
 //Intended for verbosity, debug level, etc.
 type OptCount struct {
 	Long	string
 	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
 	Short	byte
 	Count	int64
+	//Set if the option was passed, in any form, in the last parse
+	Seen	bool
+	//Least and most Count allowed after parsing.  Zero means no
+	//limit
+	MinCount	int
+	MaxCount	int
+	//Value negation stops at, or nil for none.  Setting a lower
+	//value directly, as in "--verbose=-1", is an error
+	MinValue	*int64
+	//Highest value the option can be set to directly, as in
+	//"--verbose=5", or nil for none.  Passing the option is not
+	//limited by it:  MaxCount checks the count after parsing
+	MaxValue	*int64
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
 }
 
 //Create new OptCount
 func NewOptCount(short byte, long string, help string) *OptCount {
+	return defaultParser.NewOptCount(short, long, help)
+}
+
+//Create new OptCount
+func (p *Parser) NewOptCount(short byte, long string, help string) *OptCount {
 	c := OptCount{
 		Long:	long,
 		Short:	short,
 		Help:	help,
+		parser:	p,
 	}
-	optCounts = append(optCounts, c)
-	optByShort[short] = &c
-	optByLong[long] = &c
+	p.register(&c)
 	return &c
 }
 
-const initialCapacity = 0
+//Stop negation from bringing the count below min
+func (c *OptCount) SetMinValue(min int64) *OptCount {
+	c.MinValue = &min
+	return c
+}
+
+//Stop the option from being set directly to more than max
+func (c *OptCount) SetMaxValue(max int64) *OptCount {
+	c.MaxValue = &max
+	return c
+}
+
+//Subtract one from the count, unless it is at its floor
+func (c *OptCount) decrement() {
+	if c.MinValue == nil || c.Count > *c.MinValue {
+		c.Count--
+	}
+}
+
+//Set the count from an argument, as in "--verbose=3", checking it
+//against MinValue and MaxValue
+func (c *OptCount) set(value string) error {
+	count, err := strconv.ParseInt(value, 0, 32)
+	if err != nil {
+		return parseValueErrorf(c.Long, value, "Invalid value %s for %s%s, expected an integer", value, longPrefix(), c.Long)
+	}
+	if c.MinValue != nil && count < *c.MinValue {
+		return parseValueErrorf(c.Long, value, "Invalid value %s for %s%s, expected at least %d", value, longPrefix(), c.Long, *c.MinValue)
+	}
+	if c.MaxValue != nil && count > *c.MaxValue {
+		return parseValueErrorf(c.Long, value, "Invalid value %s for %s%s, expected at most %d", value, longPrefix(), c.Long, *c.MaxValue)
+	}
+	c.Count = count
+	return nil
+}
+
+//An OptInt takes a single integer argument, set like an OptArg,
+//e.g., "--port=8080", "--port 8080", "-p8080" or "-p 8080".  The
+//number is parsed with strconv.ParseInt with a base of 0, so
+//"0x1f90" is accepted as well.  Negating it sets it to zero
+type OptInt struct {
+	Long	string
+	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
+	Short	byte
+	Value	int64
+	//Set if the option was passed, in any form, in the last parse
+	Seen	bool
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
+}
+
+//Create a new OptInt
+func NewOptInt(short byte, long string, help string) *OptInt {
+	return defaultParser.NewOptInt(short, long, help)
+}
+
+//Create a new OptInt
+func (p *Parser) NewOptInt(short byte, long string, help string) *OptInt {
+	n := OptInt{
+		Long:	long,
+		Short:	short,
+		Help:	help,
+		parser:	p,
+	}
+	p.register(&n)
+	return &n
+}
+
+//An OptFloat takes a single floating point argument, set like an
+//OptArg, e.g., "--rate=0.25", "--rate 0.25", "-r0.25" or "-r 0.25".
+//The number is parsed with strconv.ParseFloat, so "1e-3" is accepted
+//as well.  Negating it sets it to zero
+type OptFloat struct {
+	Long	string
+	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
+	Short	byte
+	Value	float64
+	//Set if the option was passed, in any form, in the last parse
+	Seen	bool
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
+}
 
-//Map of bytes to their associated options.  Used for parsing
-//short options
-var optByShort map[byte]any = make(map[byte]any, initialCapacity)
+//Create a new OptFloat
+func NewOptFloat(short byte, long string, help string) *OptFloat {
+	return defaultParser.NewOptFloat(short, long, help)
+}
 
-//Map of strings to options, used to parse long options
-var optByLong map[string]any = make(map[string]any, initialCapacity)
+//Create a new OptFloat
+func (p *Parser) NewOptFloat(short byte, long string, help string) *OptFloat {
+	x := OptFloat{
+		Long:	long,
+		Short:	short,
+		Help:	help,
+		parser:	p,
+	}
+	p.register(&x)
+	return &x
+}
 
-//List of flags created
-var flags []Flag = make([]Flag, 0, initialCapacity)
+//An OptDuration takes a single duration argument, set like an
+//OptArg, e.g., "--timeout=30s", "--timeout 1m", "-t500ms" or
+//"-t 500ms".  The duration is parsed with time.ParseDuration.
+//Negating it sets it to zero
+type OptDuration struct {
+	Long	string
+	Help	string
+	//Heading the option is listed under in the help, or "" to
+	//list it with the uncategorized options
+	Category	string
+	//If set, the option is left out of the help and usage, but
+	//is parsed as usual
+	Hidden	bool
+	Short	byte
+	Value	time.Duration
+	//Set if the option was passed, in any form, in the last parse
+	Seen	bool
+	//Called after parsing, to check this option against the others
+	Check	func(p *Parser) error
+	//Checks of options with higher priority are run first
+	Priority	int
+	//Called as soon as the option is parsed, with its argument,
+	//or "" if it has none.  An error from it ends the parse
+	OnSet	func(value string) error
+	//The parser the option was created by
+	parser	*Parser
+}
 
-//List of optArgs created
-var optArgs []OptArg = make([]OptArg, 0, initialCapacity)
+//Create a new OptDuration
+func NewOptDuration(short byte, long string, help string) *OptDuration {
+	return defaultParser.NewOptDuration(short, long, help)
+}
 
-//List of optVecs created
-var optVecs []OptVec = make([]OptVec, 0, initialCapacity)
+//Create a new OptDuration
+func (p *Parser) NewOptDuration(short byte, long string, help string) *OptDuration {
+	d := OptDuration{
+		Long:	long,
+		Short:	short,
+		Help:	help,
+		parser:	p,
+	}
+	p.register(&d)
+	return &d
+}
 
-//List of optCounts created
-var optCounts []OptCount = make([]OptCount, 0, initialCapacity)
+const initialCapacity = 0
 
-//All arguments that were not program options
+//All arguments that were not program options, from the
+//last call to ParseArgv
 var Rest []string = make([]string, 0, initialCapacity)
 
+//Index in argv just past the last option, option argument or
+//"--" taken by the last call to ParseArgv, so, e.g., a dispatcher
+//can hand argv[OptInd:] on.  0 if there were no options.  If
+//arguments were read from files, this indexes the expanded argv
+var OptInd int
+
 //Current program version, used for printing version information
 var ProgramVersion string
 
@@ -213,11 +939,40 @@ var ProgramName string
 //Description of the program
 var ProgramDesc string
 
+//Byte that introduces an option.  A short option is given by
+//one, a long option by two, and two alone end the options
+var OptionPrefix byte = '-'
+
+//Byte that introduces a negated short option, e.g., "+v".  If 0,
+//short options cannot be negated, and arguments such as "+v" go to
+//Rest.  Negating long options, as in "--verbose=false" or
+//"--no-verbose", is unaffected
+var NegationPrefix byte = '+'
+
+//Return the prefix of long options, e.g., "--"
+func longPrefix() string {
+	return string([]byte{ OptionPrefix, OptionPrefix })
+}
+
+//If set, a long option may be abbreviated to any prefix of its
+//name that is not a prefix of another's, e.g., --verb for
+//--verbose.  An exact match is always taken
+var AllowAbbrev bool
+
 //Call a function to process the argument '-'.  Normally,
 //this will involve reading and processing data from standard
-//input
+//input.  It is called wherever a bare '-' appears, unless it
+//is the argument of an option, and an error from it ends the
+//parse
 var StdinHandler = func() error { return nil }
 
+//If set, called in place of StdinHandler with StdinReader to read
+//from, so the input can be replaced, e.g., in tests
+var StdinReaderHandler func(r io.Reader) error
+
+//Reader passed to StdinReaderHandler
+var StdinReader io.Reader = os.Stdin
+
 //Convert the strings "true", "false", "t", and "f" to
 //their appropriate boolean values, case-insensitively,
 //or return an error if some other string is passed
@@ -229,98 +984,961 @@ func optargToBool(s string) (bool, error) {
 	return false, errors.New("Unable to parse boolean string passed as argument")
 }
 
-//Parse an array of strings as options
-func ParseArgv(argv []string) error {
-	expecting_optarg := false
+//Return the long name of an option
+func optLong(opt Option) string {
+	return opt.GetLong()
+}
 
-	var waiting_opt *OptArg
-	var waiting_vec *OptVec
-	expecting_opt := false
+//Set an option from a value, as given by --long=value.  Flags
+//are parsed as booleans, OptCounts, OptInts and OptFloats as
+//numbers, OptDurations as durations, and the value is appended
+//to OptVecs
+func setValue(v Option, value string) error {
+	switch v.(type) {
+	case *Flag:
+		f := v.(*Flag)
+		val, err := optargToBool(value)
+		if err != nil {
+			return parseValueErrorf(f.Long, value, "Invalid value %s for %s%s, expected a boolean", value, longPrefix(), f.Long)
+		} else {
+			f.Passed = val
+		}
+	case *OptArg:
+		return v.(*OptArg).set(value)
+	case *OptVec:
+		return v.(*OptVec).add(value)
+	case *OptCount:
+		return v.(*OptCount).set(value)
+	case *OptInt:
+		if n, err := strconv.ParseInt(value, 0, 64); err != nil {
+			return parseValueErrorf(v.(*OptInt).Long, value, "Invalid value %s for %s%s, expected an integer", value, longPrefix(), v.(*OptInt).Long)
+		} else {
+			v.(*OptInt).Value = n
+		}
+	case *OptFloat:
+		if x, err := strconv.ParseFloat(value, 64); err != nil {
+			return parseValueErrorf(v.(*OptFloat).Long, value, "Invalid value %s for %s%s, expected a number", value, longPrefix(), v.(*OptFloat).Long)
+		} else {
+			v.(*OptFloat).Value = x
+		}
+	case *OptDuration:
+		if d, err := time.ParseDuration(value); err != nil {
+			return parseValueErrorf(v.(*OptDuration).Long, value, "Invalid value %s for %s%s, expected a duration", value, longPrefix(), v.(*OptDuration).Long)
+		} else {
+			v.(*OptDuration).Value = d
+		}
+	default:
+		panic("Invalid flag type")
+	}
+	return nil
+}
 
-	for i, arg := range argv {
-		if len(arg) == 0 { continue }	//Skip empty arguments
+//Return the short name of an option
+func optShort(opt Option) byte {
+	return opt.GetShort()
+}
 
-		if expecting_opt {
-			if expecting_optarg {
-				waiting_opt.Opt = arg
-			} else {
-				waiting_vec.OptArgs = append(waiting_vec.OptArgs, arg)
-			}
-			expecting_opt = false
-			continue
+//Return the help string of an option
+func optHelp(opt Option) string {
+	return opt.GetHelp()
+}
+
+//Whether an option takes an argument from the following word
+func takesArg(opt Option) bool {
+	switch opt.(type) {
+	case *OptArg:
+		return !opt.(*OptArg).ArgOptional
+	case *OptVec, *OptInt, *OptFloat, *OptDuration:
+		return true
+	default:
+		return false
+	}
+}
+
+//Return the priority of an option
+func optPriority(opt Option) int {
+	switch opt.(type) {
+	case *Flag:
+		return opt.(*Flag).Priority
+	case *OptArg:
+		return opt.(*OptArg).Priority
+	case *OptVec:
+		return opt.(*OptVec).Priority
+	case *OptCount:
+		return opt.(*OptCount).Priority
+	case *OptInt:
+		return opt.(*OptInt).Priority
+	case *OptFloat:
+		return opt.(*OptFloat).Priority
+	case *OptDuration:
+		return opt.(*OptDuration).Priority
+	default:
+		panic("Invalid flag type")
+	}
+}
+
+//Return the check function of an option
+func optCheck(opt Option) func(p *Parser) error {
+	switch opt.(type) {
+	case *Flag:
+		return opt.(*Flag).Check
+	case *OptArg:
+		return opt.(*OptArg).Check
+	case *OptVec:
+		return opt.(*OptVec).Check
+	case *OptCount:
+		return opt.(*OptCount).Check
+	case *OptInt:
+		return opt.(*OptInt).Check
+	case *OptFloat:
+		return opt.(*OptFloat).Check
+	case *OptDuration:
+		return opt.(*OptDuration).Check
+	default:
+		panic("Invalid flag type")
+	}
+}
+
+//Return the OnSet function of an option
+func optOnSet(opt Option) func(string) error {
+	switch opt.(type) {
+	case *Flag:
+		return opt.(*Flag).OnSet
+	case *OptArg:
+		return opt.(*OptArg).OnSet
+	case *OptVec:
+		return opt.(*OptVec).OnSet
+	case *OptCount:
+		return opt.(*OptCount).OnSet
+	case *OptInt:
+		return opt.(*OptInt).OnSet
+	case *OptFloat:
+		return opt.(*OptFloat).OnSet
+	case *OptDuration:
+		return opt.(*OptDuration).OnSet
+	default:
+		panic("Invalid flag type")
+	}
+}
+
+//Call the OnSet function of an option, if it has one
+func onSet(opt Option, value string) error {
+	if f := optOnSet(opt); f != nil {
+		return f(value)
+	}
+	return nil
+}
+
+//Whether an option is left out of the help and usage
+func optHidden(opt Option) bool {
+	return opt.IsHidden()
+}
+
+//Whether an option must be passed
+func isRequired(opt Option) bool {
+	switch opt.(type) {
+	case *OptArg:
+		return opt.(*OptArg).Required
+	case *OptVec:
+		return opt.(*OptVec).Required
+	default:
+		return false
+	}
+}
+
+//Return the names of an option as given in messages, e.g.,
+//"-f/--file", or "--file" if it has no short name
+func optName(opt Option) string {
+	if optShort(opt) == 0 {
+		return longPrefix() + optLong(opt)
+	}
+	return string([]byte{ OptionPrefix, optShort(opt) }) + "/" + longPrefix() + optLong(opt)
+}
+
+//Return an error listing every required option not passed, in
+//order of long name, or nil if they all were
+func (p *Parser) checkRequired() error {
+	longs := p.optionLongs()
+	missing := make([]string, 0, initialCapacity)
+	for _, long := range longs {
+		opt := p.optByLong[long]
+		if isRequired(opt) && !p.seenOpts[opt] {
+			missing = append(missing, optName(opt))
 		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &UsageError{ &MissingRequiredError{ missing } }
+}
 
-		if len(arg) == 1 {
-			if arg[0] == '-' {
-				if e := StdinHandler(); e != nil {
-					return e
+//Return an error if the range of an option is set and n is outside
+//of it
+func checkCount(long string, what string, n int64, min int, max int) error {
+	if min != 0 && n < int64(min) {
+		return usageErrorf("Option %s%s has %s %d, below the minimum of %d", longPrefix(), long, what, n, min)
+	}
+	if max != 0 && n > int64(max) {
+		return usageErrorf("Option %s%s has %s %d, above the maximum of %d", longPrefix(), long, what, n, max)
+	}
+	return nil
+}
+
+//Return an error for the first OptVec with too few or too many
+//arguments, or OptCount with a count out of its range, in order
+//of long name
+func (p *Parser) checkCounts() error {
+	for _, long := range p.optionLongs() {
+		var err error
+		opt := p.optByLong[long]
+		switch opt.(type) {
+		case *OptVec:
+			v := opt.(*OptVec)
+			n := len(v.OptArgs)
+			if v.defaulted {
+				n = 0
+			}
+			err = checkCount(long, "argument count", int64(n), v.MinCount, v.MaxCount)
+		case *OptCount:
+			c := opt.(*OptCount)
+			err = checkCount(long, "count", c.Count, c.MinCount, c.MaxCount)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Least number of arguments, other than options, allowed in Rest
+//after parsing
+var MinArgs int
+
+//Most arguments, other than options, allowed in Rest after
+//parsing, or -1 for no limit.  Arguments after "--" count as well
+var MaxArgs = -1
+
+//Check the number of arguments in Rest against MinArgs and
+//MaxArgs, when parsing an argument vector.  Not checked when
+//parsing the options before a command, since the command's
+//arguments are in Rest then
+func (p *Parser) checkArgs() error {
+	if p.inOrder {
+		return nil
+	}
+	if len(p.Rest) < MinArgs {
+		return usageErrorf("Expected at least %d arguments, got %d", MinArgs, len(p.Rest))
+	}
+	if MaxArgs >= 0 && len(p.Rest) > MaxArgs {
+		return usageErrorf("Expected at most %d arguments, got %d", MaxArgs, len(p.Rest))
+	}
+	return nil
+}
+
+//Run the checks that can only be done once every argument has
+//been processed:  counts, exclusive groups, then each option's
+//Check function, in order of descending priority, then long name.
+//Since the checks run after every option has been set, a Check
+//can also be used to apply an option, e.g., to load a config
+//file, and the priority decides which options are applied first
+//regardless of the order they were passed in
+func (p *Parser) finishParse() error {
+	if err := p.checkRequired(); err != nil {
+		return err
+	}
+	if err := p.checkCounts(); err != nil {
+		return err
+	}
+	if err := p.checkGroups(); err != nil {
+		return err
+	}
+	names := p.optionLongs()
+	sort.Slice(names, func(i, j int) bool {
+		pi, pj := optPriority(p.optByLong[names[i]]), optPriority(p.optByLong[names[j]])
+		if pi != pj {
+			return pi > pj
+		}
+		return names[i] < names[j]
+	})
+	for _, long := range names {
+		if check := optCheck(p.optByLong[long]); check != nil {
+			if err := check(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//Parse an array of strings as options.  If help or the version
+//was requested, they are printed, and ErrHelpRequested or
+//ErrVersionRequested returned instead of any other error
+func ParseArgv(argv []string) error {
+	defaultParser.mu.Lock()
+	defer defaultParser.mu.Unlock()
+	loadDefault()
+	err := defaultParser.parse(argv, false)
+	Rest = defaultParser.Rest
+	OptInd = defaultParser.OptInd
+	return err
+}
+
+//Parse an array of strings as options, as ParseArgv does, but
+//return the arguments that are not options instead of setting
+//Rest, which is left as it is
+func ParseArgvRest(argv []string) ([]string, error) {
+	defaultParser.mu.Lock()
+	defer defaultParser.mu.Unlock()
+	loadDefault()
+	err := defaultParser.parse(argv, false)
+	OptInd = defaultParser.OptInd
+	return defaultParser.Rest, err
+}
+
+//Parse an array of strings as options.  If help or the version
+//was requested, they are printed, and ErrHelpRequested or
+//ErrVersionRequested returned instead of any other error
+func (p *Parser) ParseArgv(argv []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parse(argv, false)
+}
+
+//Parse an array of strings as options, as ParseArgv does, with
+//the parser already locked.  If dry is set, bound variables are not
+//written, help and the version are not printed, and the handlers
+//for unknown options, positional arguments and "-" are not called
+func (p *Parser) parse(argv []string, dry bool) error {
+	argv, err := expandArgFiles(argv)
+	if err != nil {
+		return err
+	}
+	return p.parseExpanded(argv, dry)
+}
+
+//Parse an array of strings as parse does, once its argument files
+//have been expanded
+func (p *Parser) parseExpanded(argv []string, dry bool) error {
+	err := p.parseArgv(argv, dry)
+	if !dry {
+		p.writeBindings()
+	}
+	if p.inOrder && len(p.Rest) > 0 {
+		//Whatever follows the command is the command's own
+		argv = argv[:p.OptInd]
+	}
+	if p.requested(p.helpFlag, argv) {
+		if !dry {
+			p.PrintHelp()
+		}
+		return ErrHelpRequested
+	}
+	if p.requested(p.versionFlag, argv) {
+		if !dry {
+			p.PrintVersion()
+		}
+		return ErrVersionRequested
+	}
+	return err
+}
+
+//Check whether argv would parse, returning the error ParseArgv
+//would, without changing any option, Rest or bound variable.  OnSet,
+//Check and the handlers for unknown options, positional arguments
+//and "-" are not called, and help and the version are not printed
+func Validate(argv []string) error {
+	defaultParser.mu.Lock()
+	defer defaultParser.mu.Unlock()
+	loadDefault()
+	return defaultParser.validate(argv)
+}
+
+//Check whether argv would parse, returning the error ParseArgv
+//would, without changing any option, Rest or bound variable.  OnSet,
+//Check and the handlers for unknown options, positional arguments
+//and "-" are not called, and help and the version are not printed
+func (p *Parser) Validate(argv []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.validate(argv)
+}
+
+//Check whether argv would parse, as Validate does, with the parser
+//already locked
+func (p *Parser) validate(argv []string) error {
+	defer p.save()()
+	return p.parse(argv, true)
+}
+
+//Save the state a parse changes, returning a function that restores
+//it.  OnSet and Check are cleared on every option until then
+func (p *Parser) save() func() {
+	restores := make([]func(), 0, len(p.opts))
+	for _, opt := range p.opts {
+		switch opt.(type) {
+		case *Flag:
+			f := opt.(*Flag)
+			saved := *f
+			f.OnSet, f.Check = nil, nil
+			restores = append(restores, func() { *f = saved })
+		case *OptArg:
+			o := opt.(*OptArg)
+			saved := *o
+			o.OnSet, o.Check = nil, nil
+			restores = append(restores, func() { *o = saved })
+		case *OptVec:
+			v := opt.(*OptVec)
+			saved := *v
+			saved.OptArgs = append([]string(nil), v.OptArgs...)
+			v.OnSet, v.Check = nil, nil
+			restores = append(restores, func() { *v = saved })
+		case *OptCount:
+			c := opt.(*OptCount)
+			saved := *c
+			c.OnSet, c.Check = nil, nil
+			restores = append(restores, func() { *c = saved })
+		case *OptInt:
+			n := opt.(*OptInt)
+			saved := *n
+			n.OnSet, n.Check = nil, nil
+			restores = append(restores, func() { *n = saved })
+		case *OptFloat:
+			x := opt.(*OptFloat)
+			saved := *x
+			x.OnSet, x.Check = nil, nil
+			restores = append(restores, func() { *x = saved })
+		case *OptDuration:
+			d := opt.(*OptDuration)
+			saved := *d
+			d.OnSet, d.Check = nil, nil
+			restores = append(restores, func() { *d = saved })
+		default:
+			panic("Invalid flag type")
+		}
+	}
+	rest, optInd, seen, read, violations := p.Rest, p.OptInd, p.seenOpts, p.readOpts, p.groupViolations
+	return func() {
+		for _, restore := range restores {
+			restore()
+		}
+		p.Rest, p.OptInd, p.seenOpts, p.readOpts, p.groupViolations = rest, optInd, seen, read, violations
+	}
+}
+
+//Whether the argument is a negative number, such as -5 or -3.14,
+//rather than options.  If any of its digits is a short option, it
+//is taken as options
+func (p *Parser) isNegativeNumber(arg string) bool {
+	if len(arg) < 2 || arg[0] != OptionPrefix {
+		return false
+	}
+	digits, dots := 0, 0
+	for i := 1; i < len(arg); i++ {
+		if _, ok := p.optByShort[arg[i]]; ok {
+			return false
+		}
+		switch {
+		case arg[i] >= '0' && arg[i] <= '9':
+			digits++
+		case arg[i] == '.':
+			dots++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && dots <= 1
+}
+
+//If set, an argument such as "-verbose" or "-name=x", which is
+//not a valid group of short options, is taken as a long option
+var SingleDashLong bool
+
+//If unset, each short option must be passed with its own prefix,
+//so "-abc" is an error where "-a -b -c" is not.  An option that
+//takes an argument may still have it attached, as in "-fFILE"
+var AllowClumping = true
+
+//If unset, a Flag given a value, as in "--force=true", is an error.
+//Negating it, as in "--no-force" or "+f", is unaffected
+var AllowFlagValues = true
+
+//Whether an option may have its argument attached to its short
+//name, as in "-fFILE"
+func takesAttached(opt Option) bool {
+	switch opt.(type) {
+	case *OptArg, *OptVec, *OptInt, *OptFloat, *OptDuration:
+		return true
+	default:
+		return false
+	}
+}
+
+//Whether the argument is a valid group of short options, e.g.,
+//"-vq", or "-vfFILE" where f takes an argument
+func (p *Parser) isShortClump(arg string) bool {
+	for i := 1; i < len(arg); {
+		v, size := p.lookupShort(arg[i:])
+		if v == nil {
+			return false
+		}
+		i += size
+		if takesAttached(v) || p.attachedCount(v, arg[i:]) {
+			return true
+		}
+	}
+	return true
+}
+
+//Whether the option in a group of short options is an OptCount
+//with a count attached, as in "-v3", where rest follows the option.
+//The count must be a number not starting with a short option
+func (p *Parser) attachedCount(opt Option, rest string) bool {
+	if _, ok := opt.(*OptCount); !ok || rest == "" {
+		return false
+	}
+	if v, _ := p.lookupShort(rest); v != nil {
+		return false
+	}
+	_, err := strconv.ParseInt(rest, 0, 32)
+	return err == nil
+}
+
+//Record that an option was passed
+func (p *Parser) see(v Option) {
+	p.seenOpts[v] = true
+	setSeen(v, true)
+}
+
+//Set the Seen field of an option, which a Flag does not have
+func setSeen(opt Option, seen bool) {
+	switch opt.(type) {
+	case *OptArg:
+		opt.(*OptArg).Seen = seen
+	case *OptVec:
+		opt.(*OptVec).Seen = seen
+	case *OptCount:
+		opt.(*OptCount).Seen = seen
+	case *OptInt:
+		opt.(*OptInt).Seen = seen
+	case *OptFloat:
+		opt.(*OptFloat).Seen = seen
+	case *OptDuration:
+		opt.(*OptDuration).Seen = seen
+	}
+}
+
+//Forget the options passed in the last parse
+func (p *Parser) clearSeen() {
+	p.seenOpts = make(map[Option]bool, initialCapacity)
+	for _, opt := range p.opts {
+		setSeen(opt, false)
+	}
+}
+
+//If set, called with each argument holding an unrecognized option,
+//e.g., "--bogus=1", or a group of short options with an unknown one
+//in it, such as "-vx", instead of failing.  None of the options in
+//such a group are set.  Returning nil carries on parsing, so the
+//handler can, e.g., collect the arguments to pass to another
+//program.  An error from it ends the parse
+var UnknownHandler func(arg string) error
+
+//If set, called with each argument that is not an option, in
+//order, instead of adding it to Rest, so arguments can be handled
+//as they come, e.g., with the options passed before each.  An error
+//from it ends the parse.  MinArgs and MaxArgs count only Rest, and
+//the options before a command are parsed without it
+var PositionalHandler func(arg string) error
+
+//If set, "--" ends the options even where an option is waiting
+//for its argument, as in "-f --", and the option is reported as
+//missing its argument.  Otherwise "--" escapes an argument after
+//it that looks like an option, so "--pattern -- -foo" sets the
+//pattern to "-foo", and is itself the argument in any other case,
+//so "-f -- x" sets f to "--".  A greedy OptVec is always ended by
+//"--"
+var TerminatorEndsOptArg bool
+
+//If set, an OptArg or OptVec waiting for its argument refuses one
+//that looks like an option, so "--file --verbose" reports --file as
+//missing its argument.  A negative number, as in "--offset -5", is
+//still taken.  The argument can still be attached, as in
+//"--file=-x", or escaped, as in "--file -- -x"
+var StrictOptArg bool
+
+//If set, ParseArgv carries on past unrecognized options and
+//arguments that are not valid for their options, and returns all
+//of them together, joined with errors.Join.  The options that could
+//be parsed are still set
+var CollectErrors bool
+
+//Whether parsing can carry on past an error
+func recoverable(err error) bool {
+	var unknown *UnknownOptionError
+	var ambiguous *AmbiguousOptionError
+	var value *ParseValueError
+	var clumped *ClumpedShortsError
+	return errors.As(err, &unknown) || errors.As(err, &ambiguous) || errors.As(err, &value) || errors.As(err, &clumped)
+}
+
+//If set, long options are matched regardless of case, so --Verbose
+//and --VERBOSE are both taken as --verbose.  While it is set,
+//creating options whose long names differ only in case panics
+var CaseInsensitiveLong bool
+
+//Return a long name as it is compared when matching options
+func foldLong(long string) string {
+	if CaseInsensitiveLong {
+		return strings.ToLower(long)
+	}
+	return long
+}
+
+//Find the option with a long name, or, if AllowAbbrev is set,
+//the only option with a long name it abbreviates.  Returns nil
+//if there is none, and an error if there are several
+func (p *Parser) lookupLong(name string) (Option, error) {
+	if v, ok := p.optByLong[name]; ok || name == "" {
+		return v, nil
+	}
+	if CaseInsensitiveLong {
+		for long, v := range p.optByLong {
+			if foldLong(long) == foldLong(name) {
+				return v, nil
+			}
+		}
+	}
+	if !AllowAbbrev {
+		return nil, nil
+	}
+	var found Option
+	distinct := make(map[Option]bool, initialCapacity)
+	matches := make([]string, 0, initialCapacity)
+	for long, v := range p.optByLong {
+		if strings.HasPrefix(foldLong(long), foldLong(name)) {
+			matches = append(matches, longPrefix() + long)
+			distinct[v] = true
+			found = v
+		}
+	}
+	if len(distinct) > 1 {
+		sort.Strings(matches)
+		return nil, &UsageError{ &AmbiguousOptionError{ name, matches } }
+	}
+	return found, nil
+}
+
+//Find the flag negated by a long name of the form "no-NAME", or
+//nil if the name is not of that form or NAME is not a flag
+func (p *Parser) lookupNegated(name string) (*Flag, error) {
+	if !strings.HasPrefix(foldLong(name), "no-") {
+		return nil, nil
+	}
+	v, err := p.lookupLong(name[len("no-"):])
+	f, _ := v.(*Flag)
+	return f, err
+}
+
+func (p *Parser) parseArgv(argv []string, dry bool) error {
+	//The program's handlers, which do nothing in a dry run
+	unknownHandler, positionalHandler := UnknownHandler, PositionalHandler
+	stdinHandler, stdinReaderHandler := StdinHandler, StdinReaderHandler
+	if dry {
+		skip := func(string) error { return nil }
+		if unknownHandler != nil {
+			unknownHandler = skip
+		}
+		if positionalHandler != nil {
+			positionalHandler = skip
+		}
+		stdinHandler, stdinReaderHandler = func() error { return nil }, nil
+	}
+	expecting_optarg := false
+
+	var waiting_opt *OptArg
+	var waiting_vec *OptVec
+	var greedy_vec *OptVec
+	//An option whose argument is set through setValue
+	var waiting_val Option
+	expecting_opt := false
+	//Whether the argument being waited for was escaped by "--"
+	escaped := false
+	//Errors collected when CollectErrors is set
+	errs := make([]error, 0, initialCapacity)
+	//Index and text of the argument being parsed, for errors
+	arg_index, arg_text := 0, ""
+	//Record a recoverable error and return nil if collecting
+	//errors, otherwise return it
+	collect := func(err error) error {
+		if err != nil && recoverable(err) {
+			err = &ArgError{ arg_index, arg_text, err }
+		}
+		if err != nil && CollectErrors && recoverable(err) {
+			errs = append(errs, err)
+			return nil
+		}
+		return err
+	}
+	//Hand an unrecognized option to unknownHandler, or report it
+	unknown := func(name string, long bool) error {
+		if unknownHandler != nil {
+			return unknownHandler(arg_text)
+		}
+		return collect(&UsageError{ &UnknownOptionError{ name, long } })
+	}
+	//Return the error for the option waiting for its argument
+	missingArg := func() error {
+		if waiting_val != nil {
+			return &UsageError{ &MissingArgError{ optShort(waiting_val), optLong(waiting_val) } }
+		} else if expecting_optarg {
+			return &UsageError{ &MissingArgError{ waiting_opt.Short, waiting_opt.Long } }
+		}
+		return &UsageError{ &MissingArgError{ waiting_vec.Short, waiting_vec.Long } }
+	}
+	//Return the collected errors, or finish the parse if there
+	//are none
+	finish := func() error {
+		if len(errs) == 1 {
+			return errs[0]
+		} else if len(errs) > 1 {
+			return errors.Join(errs...)
+		}
+		if err := p.checkArgs(); err != nil {
+			return err
+		}
+		return p.finishParse()
+	}
+	p.clearSeen()
+	p.groupViolations = nil
+	p.readOpts = make(map[Option]bool, initialCapacity)
+	p.Rest = make([]string, 0, initialCapacity)
+	p.OptInd = 0
+	//OptInd before the current argument, restored if the argument
+	//turns out not to be an option
+	prev_ind := 0
+	positional := func(arg string) error {
+		p.OptInd = prev_ind
+		if positionalHandler != nil && !p.inOrder {
+			return positionalHandler(arg)
+		}
+		p.Rest = append(p.Rest, arg)
+		return nil
+	}
+
+	//End the options at the "--" at argv[i].  Everything after it,
+	//even another "--", goes to Rest, or positionalHandler, as it is
+	terminate := func(i int) error {
+		if positionalHandler != nil && !p.inOrder {
+			for _, arg := range argv[i + 1:] {
+				if err := positionalHandler(arg); err != nil {
+					return err
 				}
+			}
+			return finish()
+		}
+		p.Rest = append(p.Rest, argv[i + 1:]...)
+		return finish()
+	}
+
+	argv_loop:
+	for i, arg := range argv {
+		if p.inOrder && len(p.Rest) > 0 {
+			//Options end at the first non-option
+			p.Rest = append(p.Rest, argv[i:]...)
+			break
+		}
+		if len(arg) == 0 { continue }	//Skip empty arguments
+		prev_ind, p.OptInd = p.OptInd, i + 1
+		arg_index, arg_text = i, arg
+
+		//A greedy OptVec is ended by "--" even before its first
+		//argument, as it is after
+		greedy_waiting := expecting_opt && !expecting_optarg && waiting_val == nil && waiting_vec.Greedy
+		if expecting_opt && (TerminatorEndsOptArg || greedy_waiting) && arg == longPrefix() {
+			//"--" is the terminator, and the option is missing
+			//its argument
+			errs = append(errs, missingArg())
+			expecting_opt = false
+			waiting_val = nil
+		}
+		if expecting_opt && !escaped && arg == longPrefix() && i < len(argv) - 1 {
+			//"--" escapes a next argument that looks like an
+			//option, which is the value whatever it looks like.
+			//Otherwise "--" is the value itself
+			if next := argv[i + 1]; len(next) > 1 && (next[0] == OptionPrefix || (NegationPrefix != 0 && next[0] == NegationPrefix)) {
+				escaped = true
+				continue
+			}
+		}
+		if expecting_opt && StrictOptArg && !escaped && waiting_val == nil && len(arg) > 1 && (arg[0] == OptionPrefix || (NegationPrefix != 0 && arg[0] == NegationPrefix)) && !p.isNegativeNumber(arg) {
+			//An option, not the argument, which is missing
+			return missingArg()
+		}
+		if expecting_opt {
+			escaped = false
+			var waiting Option
+			var err error
+			if waiting_val != nil {
+				waiting, err = waiting_val, setValue(waiting_val, arg)
+				waiting_val = nil
+			} else if expecting_optarg {
+				waiting, err = waiting_opt, waiting_opt.set(arg)
 			} else {
-				Rest = append(Rest, arg)
+				waiting, err = waiting_vec, waiting_vec.add(arg)
+				if waiting_vec.Greedy {
+					greedy_vec = waiting_vec
+				}
+			}
+			if err == nil {
+				err = onSet(waiting, arg)
+			}
+			if err = collect(err); err != nil {
+				return err
 			}
+			expecting_opt = false
 			continue
-		} else if len(arg) == 2 {
-			if arg[0] == '-' {
-				if arg[1] == '-' {
-					for j := i + 1; j < len(argv); j++{
-						Rest = append(Rest, argv[j])
+		}
+
+		if arg == longPrefix() {
+			return terminate(i)
+		}
+
+		//A greedy OptVec takes everything up to the next option
+		if greedy_vec != nil {
+			if len(arg) > 1 && (arg[0] == OptionPrefix || (NegationPrefix != 0 && arg[0] == NegationPrefix)) && !p.isNegativeNumber(arg) {
+				greedy_vec = nil
+			} else {
+				if err := greedy_vec.add(arg); err != nil {
+					if err = collect(err); err != nil {
+						return err
 					}
-					return nil
-				} else {
-					if v, ok := optByShort[arg[1]]; ok {
-						switch v.(type) {
-						case *Flag:
-							f := v.(*Flag)
-							f.Passed = true
-						case *OptArg:
-							waiting_opt = v.(*OptArg)
+					continue argv_loop
+				}
+				if err := onSet(greedy_vec, arg); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if p.isNegativeNumber(arg) {
+			if err := positional(arg); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if len(arg) == 1 {
+			if arg[0] == OptionPrefix {
+				if stdinReaderHandler != nil {
+					if e := stdinReaderHandler(StdinReader); e != nil {
+						return e
+					}
+				} else if e := stdinHandler(); e != nil {
+					return e
+				}
+			} else if err := positional(arg); err != nil {
+				return err
+			}
+			continue
+		} else if len(arg) == 2 {
+			if arg[0] == OptionPrefix {
+				if v, ok := p.optByShort[arg[1]]; ok {
+					p.see(v)
+					switch v.(type) {
+					case *Flag:
+						f := v.(*Flag)
+						f.Passed = true
+					case *OptArg:
+						o := v.(*OptArg)
+						if o.ArgOptional {
+							o.Opt = o.Implicit
+						} else {
+							waiting_opt = o
 							expecting_opt = true
 							expecting_optarg = true
-						case *OptVec:
-							waiting_vec = v.(*OptVec)
-							expecting_opt = true
-							expecting_optarg = false
-						case *OptCount:
-							c := v.(*OptCount)
-							c.Count++
-						default:
-							panic("Invalid flag type")
 						}
+					case *OptVec:
+						waiting_vec = v.(*OptVec)
+						expecting_opt = true
+						expecting_optarg = false
+					case *OptCount:
+						c := v.(*OptCount)
+						c.Count++
+					case *OptInt, *OptFloat, *OptDuration:
+						waiting_val = v
+						expecting_opt = true
+					default:
+						panic("Invalid flag type")
 					}
+					if !expecting_opt {
+						if err := onSet(v, ""); err != nil {
+							return err
+						}
+					}
+				} else if err := unknown(string(arg[1]), false); err != nil {
+					return err
 				}
-			} else if arg[0] == '+' {
-				if v, ok := optByShort[arg[1]]; ok {
+			} else if NegationPrefix != 0 && arg[0] == NegationPrefix {
+				if v, ok := p.optByShort[arg[1]]; ok {
+					p.see(v)
 					switch v.(type) {
 					case *Flag:
 						f := v.(*Flag)
 						f.Passed = false
 					case *OptArg:
-						v.(*OptArg).Opt = ""
+						o := v.(*OptArg)
+						o.Opt = o.Default
 					case *OptVec:
-						v.(*OptVec).OptArgs = make([]string, initialCapacity)
+						v.(*OptVec).restore()
 					case *OptCount:
-						v.(*OptCount).Count--
+						v.(*OptCount).decrement()
+					case *OptInt:
+						v.(*OptInt).Value = 0
+					case *OptFloat:
+						v.(*OptFloat).Value = 0
+					case *OptDuration:
+						v.(*OptDuration).Value = 0
 					default:
 						panic("Invalid flag type")
 					}
+					if err := onSet(v, ""); err != nil {
+						return err
+					}
+				} else if err := unknown(string(arg[1]), false); err != nil {
+					return err
 				}
-			} else {
-				Rest = append(Rest, arg)
+			} else if err := positional(arg); err != nil {
+				return err
 			}
 		} else { //3 or more bytes
-			if arg[0] == '-' {
-				if arg[1] == '-' {	//Long argument
+			if SingleDashLong && arg[0] == OptionPrefix && arg[1] != OptionPrefix && !p.isShortClump(arg) {
+				arg = longPrefix() + arg[1:]
+			}
+			if arg[0] == OptionPrefix {
+				if arg[1] == OptionPrefix {	//Long argument
 					equals := strings.IndexByte(arg, '=')
 					if equals == -1 {
-						if v, ok := optByLong[arg[2:]]; ok {
+						if v, err := p.lookupLong(arg[2:]); err != nil {
+							if err = collect(err); err != nil {
+								return err
+							}
+							continue argv_loop
+						} else if v != nil {
+							p.see(v)
 							switch v.(type) {
 							case *Flag:
 								f := v.(*Flag)
 								f.Passed = true
 							case *OptArg:
-								waiting_opt = v.(*OptArg)
-								expecting_opt = true
-								expecting_optarg = true
+								o := v.(*OptArg)
+								if o.ArgOptional {
+									o.Opt = o.Implicit
+								} else {
+									waiting_opt = o
+									expecting_opt = true
+									expecting_optarg = true
+								}
 							case *OptVec:
 								waiting_vec = v.(*OptVec)
 								expecting_opt = true
@@ -328,46 +1946,92 @@ func ParseArgv(argv []string) error {
 							case *OptCount:
 								c := v.(*OptCount)
 								c.Count++
+							case *OptInt, *OptFloat, *OptDuration:
+								waiting_val = v
+								expecting_opt = true
 							default:
 								panic("Invalid flag type")
 							}
+							if !expecting_opt {
+								if err := onSet(v, ""); err != nil {
+									return err
+								}
+							}
+						} else if f, err := p.lookupNegated(arg[2:]); err != nil {
+							if err = collect(err); err != nil {
+								return err
+							}
+							continue argv_loop
+						} else if f != nil {
+							p.see(f)
+							f.Passed = false
+							if err := onSet(f, ""); err != nil {
+								return err
+							}
 						} else {
-							return errors.New(fmt.Sprintf("Unrecognized long option %s", arg[2:]))
+							if err := unknown(arg[2:], true); err != nil {
+								return err
+							}
+						}
+					} else if equals == 2 {	//No name, as in "--=value"
+						if err := unknown("", true); err != nil {
+							return err
 						}
 					} else {
-						if v, ok := optByLong[arg[2:equals]]; ok {
-							switch v.(type) {
-							case *Flag:
-								f := v.(*Flag)
-								opt := arg[equals + 1:]
-								val, err := optargToBool(opt)
-								if err != nil {
+						if v, err := p.lookupLong(arg[2:equals]); err != nil {
+							if err = collect(err); err != nil {
+								return err
+							}
+							continue argv_loop
+						} else if v != nil {
+							p.see(v)
+							if _, flag := v.(*Flag); flag && !AllowFlagValues {
+								if err := collect(parseValueErrorf(optLong(v), arg[equals + 1:], "Option %s%s takes no value, got %s", longPrefix(), optLong(v), arg[equals + 1:])); err != nil {
 									return err
-								} else {
-									f.Passed = val
 								}
-							case *OptArg:
-								o := v.(*OptArg)
-								opt := arg[equals + 1:]
-								o.Opt = opt
-							case *OptVec:
-								o := v.(*OptVec)
-								opt := arg[equals + 1:]
-								o.OptArgs = append(o.OptArgs, opt)
-							case *OptCount:
-								if value, err := strconv.ParseInt(arg[equals + 1:], 0, 32); err != nil {
-									return fmt.Errorf("Unable to parse %s as a number, %s", arg[equals + 1:], arg[2:equals])
-								} else {
-									v.(*OptCount).Count = value
+								continue argv_loop
+							}
+							if err := setValue(v, arg[equals + 1:]); err != nil {
+								if err = collect(err); err != nil {
+									return err
 								}
-							default:
-								panic("Invalid flag type")
+								continue argv_loop
+							}
+							if err := onSet(v, arg[equals + 1:]); err != nil {
+								return err
+							}
+							if o, ok := v.(*OptVec); ok && o.Greedy {
+								greedy_vec = o
+							}
+						} else {
+							if err := unknown(arg[2:equals], true); err != nil {
+								return err
 							}
 						}
 					}
 				} else {		//group of shorts
+					if unknownHandler != nil && !p.isShortClump(arg) {
+						if err := unknownHandler(arg_text); err != nil {
+							return err
+						}
+						continue argv_loop
+					}
+					if first, size := p.lookupShort(arg[1:]); !AllowClumping && 1 + size < len(arg) && !takesAttached(first) && !p.attachedCount(first, arg[1 + size:]) {
+						if first == nil {
+							if err := unknown(arg[1:1 + size], false); err != nil {
+								return err
+							}
+						} else if err := collect(&UsageError{ &ClumpedShortsError{ arg } }); err != nil {
+							return err
+						}
+						continue argv_loop
+					}
 					for i := 1; i < len(arg); i++ {
-						if v, ok := optByShort[arg[i]]; ok {
+						v, size := p.lookupShort(arg[i:])
+						name := arg[i:i + size]
+						i += size - 1
+						if v != nil {
+							p.see(v)
 							switch v.(type) {
 							case *Flag:
 								f := v.(*Flag)
@@ -375,73 +2039,300 @@ func ParseArgv(argv []string) error {
 							case *OptArg:
 								o := v.(*OptArg)
 								if i < len(arg) - 1 {
-									o.Opt = arg[i + 1:]
+									if err := o.set(strings.TrimPrefix(arg[i + 1:], "=")); err != nil {
+										if err = collect(err); err != nil {
+											return err
+										}
+										continue argv_loop
+									}
+									if err := onSet(v, strings.TrimPrefix(arg[i + 1:], "=")); err != nil {
+										return err
+									}
 									goto arg_loop_end
+								} else if o.ArgOptional {
+									o.Opt = o.Implicit
 								} else {
+									waiting_opt = o
 									expecting_opt = true
 									expecting_optarg = true
 								}
 							case *OptVec:
 								o := v.(*OptVec)
 								if i < len(arg) - 1 {
-									o.OptArgs = append(o.OptArgs, arg[i + 1:])
+									if err := o.add(strings.TrimPrefix(arg[i + 1:], "=")); err != nil {
+										if err = collect(err); err != nil {
+											return err
+										}
+										continue argv_loop
+									}
+									if err := onSet(v, strings.TrimPrefix(arg[i + 1:], "=")); err != nil {
+										return err
+									}
+									if o.Greedy {
+										greedy_vec = o
+									}
 									goto arg_loop_end
 								} else {
+									waiting_vec = o
 									expecting_opt = true
 									expecting_optarg = false
 								}
 							case *OptCount:
 								c := v.(*OptCount)
+								if p.attachedCount(v, arg[i + 1:]) {
+									if err := c.set(arg[i + 1:]); err != nil {
+										if err = collect(err); err != nil {
+											return err
+										}
+										continue argv_loop
+									}
+									if err := onSet(v, arg[i + 1:]); err != nil {
+										return err
+									}
+									goto arg_loop_end
+								}
 								c.Count++
+							case *OptInt, *OptFloat, *OptDuration:
+								if i < len(arg) - 1 {
+									if err := setValue(v, strings.TrimPrefix(arg[i + 1:], "=")); err != nil {
+										if err = collect(err); err != nil {
+											return err
+										}
+										continue argv_loop
+									}
+									if err := onSet(v, strings.TrimPrefix(arg[i + 1:], "=")); err != nil {
+										return err
+									}
+									goto arg_loop_end
+								} else {
+									waiting_val = v
+									expecting_opt = true
+								}
 							default:
 								panic("Invalid flag type")
 							}
+							if !expecting_opt {
+								if err := onSet(v, ""); err != nil {
+									return err
+								}
+							}
 						} else {	//Invalid argument
-							return fmt.Errorf("Unrecognized short option:  '%c'", arg[i])
+							if err := unknown(name, false); err != nil {
+								return err
+							}
 						}
 					}
 					arg_loop_end:
 				}
-			} else if arg[0] == '+' {
+			} else if NegationPrefix != 0 && arg[0] == NegationPrefix {
+				if unknownHandler != nil && !p.isShortClump(arg) {
+					if err := unknownHandler(arg); err != nil {
+						return err
+					}
+					continue argv_loop
+				}
+				if first, size := p.lookupShort(arg[1:]); !AllowClumping && 1 + size < len(arg) {
+					if first == nil {
+						if err := unknown(arg[1:1 + size], false); err != nil {
+							return err
+						}
+					} else if err := collect(&UsageError{ &ClumpedShortsError{ arg } }); err != nil {
+						return err
+					}
+					continue argv_loop
+				}
 				for i := 1; i < len(arg); i++ {
-					if v, ok := optByShort[arg[i]]; ok {
+					v, size := p.lookupShort(arg[i:])
+					name := arg[i:i + size]
+					i += size - 1
+					if v != nil {
+						p.see(v)
 						switch v.(type) {
 						case *Flag:
 							f := v.(*Flag)
 							f.Passed = false
 						case *OptArg:
 							o := v.(*OptArg)
-							o.Opt = ""
+							o.Opt = o.Default
 						case *OptVec:
-							o := v.(*OptVec)
-							o.OptArgs = make([]string, initialCapacity)
+							v.(*OptVec).restore()
 						case *OptCount:
 							c := v.(*OptCount)
-							c.Count--
+							c.decrement()
+						case *OptInt:
+							v.(*OptInt).Value = 0
+						case *OptFloat:
+							v.(*OptFloat).Value = 0
+						case *OptDuration:
+							v.(*OptDuration).Value = 0
 						default:
 							panic("Invalid flag type")
 						}
+						if err := onSet(v, ""); err != nil {
+							return err
+						}
 					} else {	//Invalid argument
-						return fmt.Errorf("Unrecognized short option:  '%c'", arg[i])
+						if err := unknown(name, false); err != nil {
+							return err
+						}
 					}
 				}
-			} else {	//Not an option
-				Rest = append(Rest, arg)
+			} else if err := positional(arg); err != nil {	//Not an option
+				return err
 			}
 		}
 	}
 	if expecting_opt {
-		f := "Expecting argument for option:  -%c/--%s"
-		if expecting_optarg {
-			return fmt.Errorf(f, waiting_opt.Short, waiting_opt.Long)
-		} else {
-			return fmt.Errorf(f, waiting_vec.Short, waiting_vec.Long)
+		errs = append(errs, missingArg())
+	}
+	return finish()
+}
+
+//Separator used by ParseMap to split the value of an OptVec
+//into multiple arguments
+var MapSeparator = ","
+
+//Set options from a map of long names to values, as if each
+//had been passed as --long=value.  Keys are applied in sorted
+//order.  Values for OptVecs are split on MapSeparator
+func ParseMap(m map[string]string) error {
+	defaultParser.mu.Lock()
+	defer defaultParser.mu.Unlock()
+	err := defaultParser.parseMap(m)
+	Rest = defaultParser.Rest
+	OptInd = defaultParser.OptInd
+	return err
+}
+
+//Set options from a map of long names to values, as if each
+//had been passed as --long=value.  Keys are applied in sorted
+//order.  Values for OptVecs are split on MapSeparator
+func (p *Parser) ParseMap(m map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parseMap(m)
+}
+
+//Set options from a map, as ParseMap does, with the parser already
+//locked.  There are no arguments, so Rest is left empty
+func (p *Parser) parseMap(m map[string]string) error {
+	p.clearSeen()
+	p.groupViolations = nil
+	p.readOpts = make(map[Option]bool, initialCapacity)
+	p.Rest = make([]string, 0, initialCapacity)
+	p.OptInd = 0
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v, ok := p.optByLong[k]
+		if !ok {
+			return &UsageError{ &UnknownOptionError{ k, true } }
+		}
+		p.see(v)
+		if _, vec := v.(*OptVec); vec && MapSeparator != "" {
+			for _, s := range strings.Split(m[k], MapSeparator) {
+				if err := setValue(v, s); err != nil {
+					return err
+				}
+			}
+		} else if err := setValue(v, m[k]); err != nil {
+			return err
+		}
+		if err := onSet(v, m[k]); err != nil {
+			return err
 		}
-	} else {
-		return nil
 	}
+	return p.finishParse()
+}
+
+//Check that the option registry is consistent:  every short name,
+//byte or rune, and long name maps to an option with that name, and
+//every option created is reachable by its names.  Returns an
+//error describing each inconsistency, or nil.  Intended for use in
+//tests
+func AssertInvariants() error {
+	return defaultParser.AssertInvariants()
+}
+
+//Check that the option registry is consistent:  every short name,
+//byte or rune, and long name maps to an option with that name, and
+//every option created is reachable by its names.  Returns an
+//error describing each inconsistency, or nil.  Intended for use in
+//tests
+func (p *Parser) AssertInvariants() error {
+	known := make(map[Option]bool, len(p.opts))
+	errs := make([]error, 0, initialCapacity)
+	for _, opt := range p.opts {
+		known[opt] = true
+		if p.optByLong[optLong(opt)] != opt {
+			errs = append(errs, fmt.Errorf("Option --%s is not reachable by its long name", optLong(opt)))
+		}
+		if optShort(opt) != 0 && p.optByShort[optShort(opt)] != opt {
+			errs = append(errs, fmt.Errorf("Option --%s is not reachable by its short name -%c", optLong(opt), optShort(opt)))
+		}
+	}
+	shorts := make([]int, 0, len(p.optByShort))
+	for short := range p.optByShort {
+		shorts = append(shorts, int(short))
+	}
+	sort.Ints(shorts)
+	for _, short := range shorts {
+		opt := p.optByShort[byte(short)]
+		if optShort(opt) != byte(short) {
+			errs = append(errs, fmt.Errorf("Short name -%c maps to option --%s with short name -%c", short, optLong(opt), optShort(opt)))
+		}
+		if !known[opt] {
+			errs = append(errs, fmt.Errorf("Short name -%c maps to unregistered option --%s", short, optLong(opt)))
+		}
+	}
+	longs := make([]string, 0, len(p.optByLong))
+	for long := range p.optByLong {
+		longs = append(longs, long)
+	}
+	sort.Strings(longs)
+	for _, long := range longs {
+		opt := p.optByLong[long]
+		if optLong(opt) != long && !p.isAlias(opt, long) {
+			errs = append(errs, fmt.Errorf("Long name --%s maps to option --%s", long, optLong(opt)))
+		}
+		if !known[opt] {
+			errs = append(errs, fmt.Errorf("Long name --%s maps to unregistered option", long))
+		}
+	}
+	runes := make([]int, 0, len(p.optByRune))
+	for r := range p.optByRune {
+		runes = append(runes, int(r))
+	}
+	sort.Ints(runes)
+	for _, r := range runes {
+		opt := p.optByRune[rune(r)]
+		if p.shortRunes[opt] != rune(r) {
+			errs = append(errs, fmt.Errorf("Short name -%c maps to option --%s with short name -%c", r, optLong(opt), p.shortRunes[opt]))
+		}
+		if !known[opt] {
+			errs = append(errs, fmt.Errorf("Short name -%c maps to unregistered option --%s", r, optLong(opt)))
+		}
+	}
+	for _, opt := range p.opts {
+		if r, ok := p.shortRunes[opt]; ok && p.optByRune[r] != opt {
+			errs = append(errs, fmt.Errorf("Option --%s is not reachable by its short name -%c", optLong(opt), r))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+//Set the program name, version and description used for the help
+//and version information
+func SetProgramInfo(name, version, desc string) {
+	ProgramName, ProgramVersion, ProgramDesc = name, version, desc
 }
 
+//Parse os.Args, first setting ProgramName to argv[0] and
+//ProgramVersion to "0.0.1" if they are unset.  ProgramDesc is left
+//as it is, and is empty unless set
 func GetOpts() error {
 	if ProgramName == "" {
 		ProgramName = os.Args[0]
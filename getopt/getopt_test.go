@@ -1,11 +1,20 @@
 package getopt
 
 import(
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 )
 
 //Check whether bare flags passed are recognized
 func TestLongFlag(t *testing.T) {
+	Reset()
 	a := NewFlag('a', "about", "topic")
 	b := NewFlag('b', "before", "date prior")
 	c := NewFlag('c', "config", "Configuration file")
@@ -26,6 +35,7 @@ func TestLongFlag(t *testing.T) {
 
 //Check whether long opt works with equals sign
 func TestLongEqualOptArg(t *testing.T) {
+	Reset()
 	f := NewOptArg('f', "file", "file to read")
 	argv := []string { "--file=hello.txt" }
 	ParseArgv(argv)
@@ -37,6 +47,7 @@ func TestLongEqualOptArg(t *testing.T) {
 
 //Check whether long opt works with separate argument
 func TestLongSepOptArg(t *testing.T) {
+	Reset()
 	f := NewOptArg('f', "file", "file to read")
 	argv := []string { "--file", "hello.txt" }
 	ParseArgv(argv)
@@ -47,6 +58,7 @@ func TestLongSepOptArg(t *testing.T) {
 
 //Check whether flags can be set with boolean strings
 func TestLongFlagEqualBool(t *testing.T) {
+	Reset()
 	f := NewFlag('f', "force", "force action")
 	g := NewFlag('g', "global", "global change")
 	g.Passed = true
@@ -65,6 +77,7 @@ func TestLongFlagEqualBool(t *testing.T) {
 
 //Check that invalid strings cause an error
 func TestLongFlagBoolError(t *testing.T) {
+	Reset()
 	_ = NewFlag('f', "force", "force")
 	argv := []string {"--force=Fase" }
 	err := ParseArgv(argv)
@@ -75,6 +88,7 @@ func TestLongFlagBoolError(t *testing.T) {
 
 //Check that short flags work, including negating them
 func TestShortSeparate(t *testing.T) {
+	Reset()
 	a := NewFlag('a', "about", "topic")
 	b := NewFlag('b', "before", "date prior")
 	b.Passed = true
@@ -96,6 +110,7 @@ func TestShortSeparate(t *testing.T) {
 
 //Check that groups of short flags are parsed
 func TestShortTogether_pair(t *testing.T) {
+	Reset()
 	a := NewFlag('a', "about", "topic")
 	b := NewFlag('b', "before", "date prior")
 	c := NewFlag('c', "config", "Configuration file")
@@ -117,6 +132,7 @@ func TestShortTogether_pair(t *testing.T) {
 
 //Check if longer clump works
 func TestShortTogether_triple(t *testing.T) {
+	Reset()
 	a := NewFlag('a', "about", "topic")
 	b := NewFlag('b', "before", "date prior")
 	c := NewFlag('c', "config", "Configuration file")
@@ -138,6 +154,7 @@ func TestShortTogether_triple(t *testing.T) {
 
 //Check if clump of negated flags works
 func TestShortClumpNegate(t *testing.T) {
+	Reset()
 	a := NewFlag('a', "about", "topic")
 	b := NewFlag('b', "before", "date prior")
 	c := NewFlag('c', "config", "Configuration file")
@@ -171,7 +188,56 @@ func TestStdin(t *testing.T) {
 	}
 }
 
+//Check that '-' passes StdinReader to StdinReaderHandler in place of
+//calling StdinHandler
+func TestStdinReader(t *testing.T) {
+	defer func(h func() error, r io.Reader) { StdinHandler, StdinReaderHandler, StdinReader = h, nil, r }(StdinHandler, StdinReader)
+	StdinHandler = func() error {
+		t.Fatal("StdinHandler called with StdinReaderHandler set")
+		return nil
+	}
+	var read []byte
+	StdinReaderHandler = func(r io.Reader) error {
+		var err error
+		read, err = io.ReadAll(r)
+		return err
+	}
+	StdinReader = bytes.NewReader([]byte("line one\nline two\n"))
+	p := NewParser()
+	if err := p.ParseArgv([]string{ "-" }); err != nil || string(read) != "line one\nline two\n" {
+		t.Fatalf("Expected the reader's contents, got %q, %v", read, err)
+	}
+}
+
+//Check that '-' after other options calls the function once, and
+//that its error is returned
+func TestStdinAfterFlag(t *testing.T) {
+	defer func(h func() error) { StdinHandler = h }(StdinHandler)
+	p := NewParser()
+	q := p.NewFlag('q', "quiet", "print less")
+	calls := 0
+	StdinHandler = func() error {
+		calls++
+		return nil
+	}
+	if err := p.ParseArgv([]string{ "-q", "-", "file" }); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("Expected one call, got %d", calls)
+	}
+	if !q.Passed || len(p.Rest) != 1 || p.Rest[0] != "file" {
+		t.Fatalf("Expected -q and [file], got %t %v", q.Passed, p.Rest)
+	}
+	failed := errors.New("stdin failed")
+	StdinHandler = func() error { return failed }
+	if err := p.ParseArgv([]string{ "-q", "-" }); err != failed {
+		t.Fatalf("Expected handler error, got %v", err)
+	}
+}
+
 func TestOptCountShort(t *testing.T) {
+	Reset()
 	v := NewOptCount('v', "verbose", "Verbosity of the program")
 	argv := []string { "-vvv" }
 	ParseArgv(argv)
@@ -181,6 +247,7 @@ func TestOptCountShort(t *testing.T) {
 }
 
 func TestOptCountLong(t *testing.T) {
+	Reset()
 	v := NewOptCount('v', "verbose", "Verbosity of the program")
 	argv := []string { "--verbose", "--verbose", "--verbose" }
 	ParseArgv(argv)
@@ -190,6 +257,7 @@ func TestOptCountLong(t *testing.T) {
 }
 
 func TestOptCountEquals(t *testing.T) {
+	Reset()
 	v := NewOptCount('v', "verbose", "Verbosity of the program")
 	argv := []string { "--verbose=3" }
 	ParseArgv(argv)
@@ -200,6 +268,7 @@ func TestOptCountEquals(t *testing.T) {
 
 //Test that opt that takes arg works when connected as short opt
 func TestShortOptArgConn(t *testing.T) {
+	Reset()
 	f := NewOptArg('f', "file", "file to process")
 	argv := []string { "-fhello.txt" }
 	ParseArgv(argv)
@@ -210,6 +279,7 @@ func TestShortOptArgConn(t *testing.T) {
 
 //Test that arguments passed between options get added to rest
 func TestRest(t *testing.T) {
+	Reset()
 	f := NewFlag('f', "file", "file to process")
 	argv := []string { "hello", "--file", "world" }
 	Rest = make([]string, initialCapacity)
@@ -230,6 +300,7 @@ func TestRest(t *testing.T) {
 
 //Test the -- passes everything to Rest
 func TestAllRest(t *testing.T) {
+	Reset()
 	f := NewFlag('f', "file", "file to process")
 	argv := []string { "hello", "--", "--file", "-f", "world" }
 	Rest = make([]string, initialCapacity)
@@ -244,3 +315,1895 @@ func TestAllRest(t *testing.T) {
 		t.Fatalf("Expected '--file', got %s", Rest[1])
 	}
 }
+
+//Test that options can be set from a map
+func TestParseMap(t *testing.T) {
+	Reset()
+	f := NewOptArg('f', "file", "file to process")
+	v := NewFlag('v', "verbose", "print more")
+	i := NewOptVec('i', "include", "directories to include")
+	err := ParseMap(map[string]string{
+		"file": "hello.txt",
+		"verbose": "true",
+		"include": "a,b",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if f.Opt != "hello.txt" {
+		t.Fatalf("Expected 'hello.txt', got %s", f.Opt)
+	}
+	if !v.Passed {
+		t.Fatal("Expected verbose to be set")
+	}
+	if len(i.OptArgs) != 2 || i.OptArgs[0] != "a" || i.OptArgs[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", i.OptArgs)
+	}
+	if err := ParseMap(map[string]string{ "bogus": "x" }); err == nil {
+		t.Fatal("Expected error for unknown option")
+	}
+	if err := ParseMap(map[string]string{ "verbose": "maybe" }); err == nil {
+		t.Fatal("Expected error for bad boolean")
+	}
+}
+
+//Test that a check can compare an option against another
+func TestCheck(t *testing.T) {
+	Reset()
+	min := NewOptCount('m', "min", "lower bound")
+	max := NewOptCount('M', "max", "upper bound")
+	max.Check = func(p *Parser) error {
+		if max.Count < min.Count {
+			return fmt.Errorf("--max must not be less than --min")
+		}
+		return nil
+	}
+	defer func() { max.Check = nil }()
+	if err := ParseArgv([]string{ "--min=2", "--max=5" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if err := ParseArgv([]string{ "--max=1", "--min=5" }); err == nil {
+		t.Fatal("Expected error when --max is less than --min")
+	}
+	if err := ParseArgv([]string{ "--min=0", "--max=1", "--", "--min=5" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+}
+
+//Test that checks are applied in order of priority
+func TestCheckPriority(t *testing.T) {
+	Reset()
+	settings := make(map[string]string)
+	set := NewOptVec('s', "set", "set a value")
+	config := NewOptArg('C', "config", "config file")
+	set.Check = func(p *Parser) error {
+		for _, kv := range set.OptArgs {
+			settings[kv] = "set"
+		}
+		return nil
+	}
+	config.Check = func(p *Parser) error {
+		settings["color"] = "config"
+		return nil
+	}
+	defer func() { set.Check, config.Check = nil, nil }()
+	config.Priority = 1
+	if err := ParseArgv([]string{ "--set", "color", "--config", "file" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if settings["color"] != "set" {
+		t.Fatalf("Expected --set to win, got %s", settings["color"])
+	}
+	config.Priority = -1
+	if err := ParseArgv([]string{ "--config", "file" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if settings["color"] != "config" {
+		t.Fatalf("Expected --config to win, got %s", settings["color"])
+	}
+}
+
+//Test that a broken registry is caught
+func TestAssertInvariants(t *testing.T) {
+	p := NewParser()
+	f := p.NewFlag('f', "force", "force action")
+	p.NewOptArg('o', "output", "output file")
+	if err := p.AssertInvariants(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	p.optByShort['o'] = f
+	if err := p.AssertInvariants(); err == nil {
+		t.Fatal("Expected error for short name mapping to the wrong option")
+	}
+	p.optByShort['o'] = p.optByLong["output"]
+	p.opts = append(p.opts, &Flag{ Long: "force", Short: 'q' })
+	if err := p.AssertInvariants(); err == nil {
+		t.Fatal("Expected error for option shadowed by another")
+	}
+
+	p = NewParser()
+	e := p.NewFlag(0, "einzel", "single").SetShortRune('é')
+	p.NewFlag(0, "zwei", "double").SetShortRune('ü')
+	if err := p.AssertInvariants(); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	p.optByRune['ü'] = e
+	if err := p.AssertInvariants(); err == nil {
+		t.Fatal("Expected error for rune short name mapping to the wrong option")
+	}
+	p.optByRune['ü'] = p.optByLong["zwei"]
+	delete(p.optByRune, 'é')
+	if err := p.AssertInvariants(); err == nil {
+		t.Fatal("Expected error for option unreachable by its rune short name")
+	}
+}
+
+//Test each form of an option with an optional argument
+func TestArgOptional(t *testing.T) {
+	Reset()
+	c := NewOptArg('C', "color", "when to use color")
+	c.ArgOptional = true
+	c.Implicit = "auto"
+	forms := []struct {
+		argv	[]string
+		opt	string
+		rest	int
+	}{
+		{ []string{ "--color", "file" }, "auto", 1 },
+		{ []string{ "--color=always", "file" }, "always", 1 },
+		{ []string{ "-C", "file" }, "auto", 1 },
+		{ []string{ "-Calways", "file" }, "always", 1 },
+	}
+	for _, form := range forms {
+		c.Opt = ""
+		Rest = make([]string, initialCapacity)
+		if err := ParseArgv(form.argv); err != nil {
+			t.Fatalf("%v: expected no error, got %s", form.argv, err)
+		}
+		if c.Opt != form.opt {
+			t.Fatalf("%v: expected '%s', got '%s'", form.argv, form.opt, c.Opt)
+		}
+		if len(Rest) != form.rest {
+			t.Fatalf("%v: expected the following argument in Rest, got %v", form.argv, Rest)
+		}
+	}
+}
+
+//Test that an option taking an argument at the end of a clump
+//takes the following argument
+func TestShortClumpOptArg(t *testing.T) {
+	Reset()
+	a := NewFlag('a', "about", "topic")
+	f := NewOptArg('f', "file", "file to process")
+	if err := ParseArgv([]string{ "-af", "hello.txt" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !a.Passed {
+		t.Fatal("'a' was passed, should be true")
+	}
+	if f.Opt != "hello.txt" {
+		t.Fatalf("Expected hello.txt, got %s", f.Opt)
+	}
+}
+
+//Test parsing with a different option prefix
+func TestOptionPrefix(t *testing.T) {
+	Reset()
+	OptionPrefix = '/'
+	NegationPrefix = '~'
+	defer func() { OptionPrefix, NegationPrefix = '-', '+' }()
+	a := NewFlag('a', "about", "topic")
+	b := NewFlag('b', "before", "date prior")
+	f := NewOptArg('f', "file", "file to process")
+	b.Passed = true
+	Rest = make([]string, initialCapacity)
+	argv := []string{ "/a", "~b", "//file", "hello.txt", "-x", "//", "/a" }
+	if err := ParseArgv(argv); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !a.Passed {
+		t.Fatal("'a' was passed, should be true")
+	}
+	if b.Passed {
+		t.Fatal("'b' was negated, should be false")
+	}
+	if f.Opt != "hello.txt" {
+		t.Fatalf("Expected hello.txt, got %s", f.Opt)
+	}
+	if len(Rest) != 2 || Rest[0] != "-x" || Rest[1] != "/a" {
+		t.Fatalf("Expected [-x /a] in Rest, got %v", Rest)
+	}
+	NewExclusiveGroup("when", false, a, b)
+	cases := []struct {
+		argv	[]string
+		message	string
+	}{
+		{ []string{ "/f" }, "Expecting argument for option:  /f///file" },
+		{ []string{ "/a", "/b" }, "Exclusive group violation:  //about, //before are mutually exclusive" },
+		{ []string{ "//about=maybe" }, `Argument 0 ("//about=maybe"):  Invalid value maybe for //about, expected a boolean` },
+	}
+	for _, c := range cases {
+		if err := ParseArgv(c.argv); err == nil || err.Error() != c.message {
+			t.Fatalf("Expected %q for %v, got %v", c.message, c.argv, err)
+		}
+	}
+}
+
+//Test changing and disabling the negation prefix
+func TestNegationPrefix(t *testing.T) {
+	defer func() { NegationPrefix = '+' }()
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	q := p.NewFlag('q', "quiet", "print less")
+	cases := []struct {
+		prefix	byte
+		argv	[]string
+		rest	string
+	}{
+		{ '+', []string{ "+v", "+vq", "x" }, "[x]" },
+		{ '^', []string{ "^v", "+v", "^q" }, "[+v]" },
+		{ 0, []string{ "+v", "+vq", "--verbose=false", "--no-quiet" }, "[+v +vq]" },
+	}
+	for _, c := range cases {
+		NegationPrefix = c.prefix
+		v.Passed, q.Passed = true, true
+		if err := p.ParseArgv(c.argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", c.argv, err)
+		}
+		if v.Passed || q.Passed || fmt.Sprint(p.Rest) != c.rest {
+			t.Fatalf("Expected both negated and %s in Rest for %v, got %t %t %v", c.rest, c.argv, v.Passed, q.Passed, p.Rest)
+		}
+	}
+}
+
+//Test that flags are listed together in compact help
+func TestCompactFlags(t *testing.T) {
+	CompactFlags = true
+	defer func() { CompactFlags = false }()
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+
+	p.NewFlag('a', "all", "everything")
+	p.NewFlag('b', "brief", "less output")
+	p.NewOptCount('v', "verbose", "verbosity")
+	p.NewFlag(0, "quiet", "no output")
+	p.NewFlag(0, "dry-run", "do nothing")
+	p.NewOptArg('f', "file", "file to read")
+	expected := "prog - 1.0\n" +
+		"Does things\n" +
+		"-a -b -v  --dry-run --quiet\n" +
+		"-f/--file  file to read\n"
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+//Test that compact help uses OptionPrefix and wraps on runes
+func TestCompactFlagsPrefix(t *testing.T) {
+	CompactFlags, OptionPrefix, HelpWidth = true, '/', 8
+	defer func() { CompactFlags, OptionPrefix, HelpWidth = false, '-', 0 }()
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion = "prog", "1.0"
+
+	p.NewFlag('a', "all", "everything")
+	p.NewFlag(0, "einzel", "single").SetShortRune('é')
+	p.NewFlag('b', "brief", "less output")
+	p.NewFlag(0, "quiet", "no output")
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if lines := strings.Split(b.String(), "\n"); lines[1] != "/a /b /é" || lines[2] != "//quiet" {
+		t.Fatalf("Expected \"/a /b /é\" then \"//quiet\", got:\n%s", b.String())
+	}
+}
+
+//Test that "--" ends a greedy OptVec, and the rest go to Rest
+func TestGreedyTerminator(t *testing.T) {
+	Reset()
+	c := NewOptVec('c', "cmd", "command to run")
+	c.Greedy = true
+	v := NewFlag('v', "verbose", "print more")
+	Rest = make([]string, initialCapacity)
+	if err := ParseArgv([]string{ "--cmd", "a", "b", "--", "c", "-v" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(c.OptArgs) != 2 || c.OptArgs[0] != "a" || c.OptArgs[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", c.OptArgs)
+	}
+	if len(Rest) != 2 || Rest[0] != "c" || Rest[1] != "-v" {
+		t.Fatalf("Expected [c -v] in Rest, got %v", Rest)
+	}
+	if v.Passed {
+		t.Fatal("'-v' after '--' should not be parsed")
+	}
+}
+
+//Test that a greedy OptVec stops at the next option
+func TestGreedyNextOption(t *testing.T) {
+	Reset()
+	c := NewOptVec('c', "cmd", "command to run")
+	c.Greedy = true
+	v := NewFlag('v', "verbose", "print more")
+	Rest = make([]string, initialCapacity)
+	if err := ParseArgv([]string{ "-ca", "b", "-v", "d" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(c.OptArgs) != 2 || c.OptArgs[0] != "a" || c.OptArgs[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", c.OptArgs)
+	}
+	if !v.Passed {
+		t.Fatal("'-v' should end the greedy option and be parsed")
+	}
+	if len(Rest) != 1 || Rest[0] != "d" {
+		t.Fatalf("Expected [d] in Rest, got %v", Rest)
+	}
+}
+
+//Test that a greedy OptVec takes every argument to the end, and
+//that without Greedy each option takes only one
+func TestGreedyEndOfArgs(t *testing.T) {
+	p := NewParser()
+	files := p.NewOptVec('f', "files", "files to read")
+	files.Greedy = true
+	if err := p.ParseArgv([]string{ "--files", "a", "b", "c" }); err != nil || fmt.Sprint(files.OptArgs) != "[a b c]" || len(p.Rest) != 0 {
+		t.Fatalf("Expected [a b c] and nothing in Rest, got %v %v, %v", files.OptArgs, p.Rest, err)
+	}
+	q := NewParser()
+	files = q.NewOptVec('f', "files", "files to read")
+	if err := q.ParseArgv([]string{ "--files", "a", "b", "-f", "c" }); err != nil || fmt.Sprint(files.OptArgs) != "[a c]" || fmt.Sprint(q.Rest) != "[b]" {
+		t.Fatalf("Expected [a c] and [b] in Rest, got %v %v, %v", files.OptArgs, q.Rest, err)
+	}
+}
+
+//Test that Reset forgets options and results of earlier parses
+func TestReset(t *testing.T) {
+	Reset()
+	f := NewOptArg('f', "file", "file to process")
+	ProgramName = "prog"
+	if err := ParseArgv([]string{ "--file", "a.txt", "hello" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if f.Opt != "a.txt" || len(Rest) != 1 {
+		t.Fatalf("Expected a.txt and one positional, got %s and %v", f.Opt, Rest)
+	}
+	Reset()
+	if len(Rest) != 0 || ProgramName != "" {
+		t.Fatal("Expected Rest and ProgramName to be cleared")
+	}
+	if err := ParseArgv([]string{ "--file", "b.txt" }); err == nil {
+		t.Fatal("Expected --file to be unrecognized after Reset")
+	}
+	g := NewFlag('f', "file", "fresh flag")
+	if err := ParseArgv([]string{ "-f", "world" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !g.Passed || f.Opt != "a.txt" {
+		t.Fatal("Expected the fresh flag to be set and the old option untouched")
+	}
+	if len(Rest) != 1 || Rest[0] != "world" {
+		t.Fatalf("Expected [world] in Rest, got %v", Rest)
+	}
+	if err := AssertInvariants(); err != nil {
+		t.Fatalf("Expected consistent registry, got %s", err)
+	}
+}
+
+//Test that positional arguments do not accumulate across parses
+func TestRestCleared(t *testing.T) {
+	Reset()
+	NewFlag('f', "file", "file to process")
+	ParseArgv([]string{ "hello" })
+	ParseArgv([]string{ "world" })
+	if len(Rest) != 1 || Rest[0] != "world" {
+		t.Fatalf("Expected [world] in Rest, got %v", Rest)
+	}
+}
+
+//Test that --no- negates a flag
+func TestLongNoPrefix(t *testing.T) {
+	p := NewParser()
+	f := p.NewFlag('f', "force", "force action")
+	v := p.NewOptCount('v', "verbose", "verbosity")
+	f.Passed = true
+	if err := p.ParseArgv([]string{ "--no-force" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if f.Passed {
+		t.Fatal("Expected --no-force to set force to false")
+	}
+	if err := p.ParseArgv([]string{ "--no-verbose" }); err == nil {
+		t.Fatal("Expected --no- to be refused for an OptCount")
+	}
+	if v.Count != 0 {
+		t.Fatalf("Expected verbosity of 0, got %d", v.Count)
+	}
+}
+
+//Test that a registered option named no-... wins over negation
+func TestLongNoPrefixRegistered(t *testing.T) {
+	p := NewParser()
+	f := p.NewFlag('f', "force", "force action")
+	n := p.NewFlag('n', "no-force", "a separate option")
+	f.Passed = true
+	if err := p.ParseArgv([]string{ "--no-force" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !n.Passed {
+		t.Fatal("Expected --no-force to set the registered option")
+	}
+	if !f.Passed {
+		t.Fatal("Expected force to be left alone")
+	}
+}
+
+//Test that negative numbers are taken as positional arguments
+func TestNegativeNumbers(t *testing.T) {
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	if err := p.ParseArgv([]string{ "-5", "-v", "-3.14", "-", "-.5", "-1.2.3" }); err == nil {
+		t.Fatal("Expected error for -1.2.3")
+	}
+	if err := p.ParseArgv([]string{ "-5", "-v", "-3.14", "-.5" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !v.Passed {
+		t.Fatal("Expected -v to be set")
+	}
+	expected := []string{ "-5", "-3.14", "-.5" }
+	if len(p.Rest) != len(expected) {
+		t.Fatalf("Expected %v in Rest, got %v", expected, p.Rest)
+	}
+	for i := range expected {
+		if p.Rest[i] != expected[i] {
+			t.Fatalf("Expected %v in Rest, got %v", expected, p.Rest)
+		}
+	}
+}
+
+//Test that a registered digit is still taken as an option
+func TestDigitOption(t *testing.T) {
+	p := NewParser()
+	one := p.NewFlag('1', "single", "one column")
+	if err := p.ParseArgv([]string{ "-1", "-42" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !one.Passed {
+		t.Fatal("Expected -1 to set the option")
+	}
+	if len(p.Rest) != 1 || p.Rest[0] != "-42" {
+		t.Fatalf("Expected [-42] in Rest, got %v", p.Rest)
+	}
+}
+
+//Test that a missing required option is reported
+func TestRequiredMissing(t *testing.T) {
+	p := NewParser()
+	p.NewOptArg('f', "file", "file to read").SetRequired(true)
+	err := p.ParseArgv([]string{ "hello" })
+	var missing *MissingRequiredError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Expected MissingRequiredError, got %v", err)
+	}
+	if len(missing.Options) != 1 || missing.Options[0] != "-f/--file" {
+		t.Fatalf("Expected [-f/--file], got %v", missing.Options)
+	}
+}
+
+//Test that every missing required option is reported together
+func TestRequiredMultiple(t *testing.T) {
+	p := NewParser()
+	p.NewOptArg('f', "file", "file to read").SetRequired(true)
+	p.NewOptVec('I', "include", "include directory").SetRequired(true)
+	p.NewOptArg('o', "output", "output file")
+	err := p.ParseArgv([]string{ "-o", "out" })
+	if err == nil || err.Error() != "Missing required options:  -f/--file, -I/--include" {
+		t.Fatalf("Expected both options reported, got %v", err)
+	}
+}
+
+//Test that required options are satisfied, even by an empty value
+func TestRequiredSatisfied(t *testing.T) {
+	p := NewParser()
+	p.NewOptArg('f', "file", "file to read").SetRequired(true)
+	p.NewOptVec('I', "include", "include directory").SetRequired(true)
+	if err := p.ParseArgv([]string{ "-Idir", "--file=" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if err := p.ParseArgv([]string{ "--file", "a", "--include", "b" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+}
+
+//Test that an OptArg holds its default until passed
+func TestOptArgDefault(t *testing.T) {
+	p := NewParser()
+	f := p.NewOptArgDefault('f', "file", "file to read", "in.txt")
+	if err := p.ParseArgv([]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "in.txt" {
+		t.Fatalf("Expected default in.txt, got '%s'", f.Opt)
+	}
+	if err := p.ParseArgv([]string{ "-f", "other.txt" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "other.txt" {
+		t.Fatalf("Expected other.txt, got '%s'", f.Opt)
+	}
+	if err := p.ParseArgv([]string{ "+f" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "in.txt" {
+		t.Fatalf("Expected +f to restore in.txt, got '%s'", f.Opt)
+	}
+	f.Opt = "x"
+	p.NewFlag('v', "verbose", "print more")
+	if err := p.ParseArgv([]string{ "+vf" }); err != nil || f.Opt != "in.txt" {
+		t.Fatalf("Expected +vf to restore in.txt, got '%s', %v", f.Opt, err)
+	}
+}
+
+//Test that an OptVec's defaults are replaced, not appended to
+func TestOptVecDefault(t *testing.T) {
+	p := NewParser()
+	v := p.NewOptVec('I', "include", "include directory").SetDefault([]string{ "/usr/include" })
+	if err := p.ParseArgv([]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.OptArgs) != 1 || v.OptArgs[0] != "/usr/include" {
+		t.Fatalf("Expected default [/usr/include], got %v", v.OptArgs)
+	}
+	if err := p.ParseArgv([]string{ "-Ia", "--include", "b" }); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.OptArgs) != 2 || v.OptArgs[0] != "a" || v.OptArgs[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", v.OptArgs)
+	}
+	if len(v.Default) != 1 || v.Default[0] != "/usr/include" {
+		t.Fatalf("Expected Default untouched, got %v", v.Default)
+	}
+}
+
+//Test that Seen is set by every form of passing an option
+func TestSeen(t *testing.T) {
+	p := NewParser()
+	f := p.NewOptArg('f', "file", "file to read")
+	v := p.NewOptVec('I', "include", "include directory")
+	c := p.NewOptCount('v', "verbose", "print more")
+	p.NewFlag('q', "quiet", "print less")
+	if f.Seen || v.Seen || c.Seen {
+		t.Fatal("Expected nothing seen before parsing")
+	}
+	argvs := [][]string{
+		{ "-f", "a", "-I", "b", "-v" },
+		{ "--file", "a", "--include", "b", "--verbose" },
+		{ "--file=a", "--include=b", "--verbose=1" },
+		{ "-qfa", "-qIb", "-qv" },
+		{ "+f", "+I", "+v" },
+		{ "+qfIv" },
+	}
+	for _, argv := range argvs {
+		if err := p.ParseArgv(argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", argv, err)
+		}
+		if !f.Seen || !v.Seen || !c.Seen {
+			t.Fatalf("Expected all seen for %v, got %t %t %t", argv, f.Seen, v.Seen, c.Seen)
+		}
+	}
+	if err := p.ParseArgv([]string{ "-q" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Seen || v.Seen || c.Seen {
+		t.Fatal("Expected Seen to be cleared by the next parse")
+	}
+}
+
+//Test that an attached short value may be given after '='
+func TestShortEquals(t *testing.T) {
+	p := NewParser()
+	f := p.NewOptArg('f', "file", "file to read")
+	v := p.NewOptVec('I', "include", "include directory")
+	if err := p.ParseArgv([]string{ "-f=hello.txt", "-I=a", "-Ib" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "hello.txt" {
+		t.Fatalf("Expected hello.txt, got '%s'", f.Opt)
+	}
+	if len(v.OptArgs) != 2 || v.OptArgs[0] != "a" || v.OptArgs[1] != "b" {
+		t.Fatalf("Expected [a b], got %v", v.OptArgs)
+	}
+	if err := p.ParseArgv([]string{ "-fhello.txt" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "hello.txt" {
+		t.Fatalf("Expected hello.txt, got '%s'", f.Opt)
+	}
+	if err := p.ParseArgv([]string{ "-f==x" }); err != nil {
+		t.Fatal(err)
+	}
+	if f.Opt != "=x" {
+		t.Fatalf("Expected only one '=' stripped, got '%s'", f.Opt)
+	}
+}
+
+//Test that an unknown long option is an error with or without a value
+func TestUnknownLongValue(t *testing.T) {
+	p := NewParser()
+	p.NewOptArg('f', "file", "file to read")
+	for _, arg := range []string{ "--notreal", "--notreal=x" } {
+		err := p.ParseArgv([]string{ arg })
+		if err == nil {
+			t.Fatalf("Expected error for %s", arg)
+		}
+		if err.Error() != fmt.Sprintf("Argument 0 (%q):  Unrecognized long option notreal", arg) {
+			t.Fatalf("Unexpected error for %s: %s", arg, err)
+		}
+	}
+}
+
+//Test that OptInt parses decimal and hexadecimal in every form
+func TestOptInt(t *testing.T) {
+	p := NewParser()
+	port := p.NewOptInt('p', "port", "port to listen on")
+	p.NewFlag('q', "quiet", "print less")
+	argvs := map[int64][][]string{
+		8080: {
+			{ "--port=8080" },
+			{ "--port", "8080" },
+			{ "-p8080" },
+			{ "-p", "8080" },
+			{ "-qp8080" },
+			{ "-qp", "8080" },
+		},
+		0x1f91: {
+			{ "--port=0x1f91" },
+			{ "-p", "0x10", "-p0x1f91" },
+		},
+		-1: {
+			{ "--port", "-1" },
+		},
+	}
+	for want, list := range argvs {
+		for _, argv := range list {
+			if err := p.ParseArgv(argv); err != nil {
+				t.Fatalf("Unexpected error for %v: %s", argv, err)
+			}
+			if port.Value != want {
+				t.Fatalf("Expected %d for %v, got %d", want, argv, port.Value)
+			}
+		}
+	}
+	if err := p.ParseArgv([]string{ "+p" }); err != nil || port.Value != 0 {
+		t.Fatalf("Expected +p to set 0, got %d, %v", port.Value, err)
+	}
+}
+
+//Test that a malformed or missing OptInt argument is an error
+func TestOptIntInvalid(t *testing.T) {
+	p := NewParser()
+	p.NewOptInt('p', "port", "port to listen on")
+	for _, argv := range [][]string{ { "--port=http" }, { "--port", "80a" }, { "-pfoo" }, { "-p" } } {
+		err := p.ParseArgv(argv)
+		if err == nil {
+			t.Fatalf("Expected error for %v", argv)
+		}
+		if !errors.As(err, new(*UsageError)) {
+			t.Fatalf("Expected UsageError for %v, got %T", argv, err)
+		}
+	}
+}
+
+//Test that OptFloat parses its argument in every form
+func TestOptFloat(t *testing.T) {
+	p := NewParser()
+	rate := p.NewOptFloat('r', "rate", "sampling rate")
+	for _, argv := range [][]string{ { "--rate=0.25" }, { "--rate", "0.25" }, { "-r0.25" }, { "-r", "0.25" }, { "-r=25e-2" } } {
+		if err := p.ParseArgv(argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", argv, err)
+		}
+		if rate.Value != 0.25 {
+			t.Fatalf("Expected 0.25 for %v, got %g", argv, rate.Value)
+		}
+	}
+	err := p.ParseArgv([]string{ "--rate", "fast" })
+	if err == nil {
+		t.Fatal("Expected error for non-numeric rate")
+	}
+	if err.Error() != `Argument 1 ("fast"):  Invalid value fast for --rate, expected a number` {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := p.ParseArgv([]string{ "-rx" }); err == nil {
+		t.Fatal("Expected error for -rx")
+	}
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if !bytes.Contains(b.Bytes(), []byte("-r/--rate")) {
+		t.Fatalf("Expected --rate in help, got %q", b.String())
+	}
+}
+
+//Test that OptDuration parses durations in every form
+func TestOptDuration(t *testing.T) {
+	p := NewParser()
+	timeout := p.NewOptDuration('t', "timeout", "time to wait")
+	cases := []struct {
+		argv	[]string
+		value	time.Duration
+	}{
+		{ []string{ "--timeout=30s" }, 30 * time.Second },
+		{ []string{ "--timeout", "1m" }, time.Minute },
+		{ []string{ "-t500ms" }, 500 * time.Millisecond },
+		{ []string{ "-t", "1h30m" }, 90 * time.Minute },
+		{ []string{ "-t", "-2s" }, -2 * time.Second },
+		{ []string{ "+t" }, 0 },
+	}
+	for _, c := range cases {
+		timeout.Value = time.Hour
+		if err := p.ParseArgv(c.argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", c.argv, err)
+		}
+		if timeout.Value != c.value {
+			t.Fatalf("Expected %s for %v, got %s", c.value, c.argv, timeout.Value)
+		}
+	}
+	err := p.ParseArgv([]string{ "--timeout", "soon" })
+	if err == nil || err.Error() != `Argument 1 ("soon"):  Invalid value soon for --timeout, expected a duration` {
+		t.Fatalf("Expected an error naming --timeout, got %v", err)
+	}
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if !bytes.Contains(b.Bytes(), []byte("-t/--timeout  time to wait")) {
+		t.Fatalf("Expected --timeout in help, got %q", b.String())
+	}
+}
+
+//Test that an OptArg with choices rejects other values
+func TestOptArgChoices(t *testing.T) {
+	p := NewParser()
+	format := p.NewOptArg('F', "format", "output format").SetChoices("json", "yaml", "xml")
+	name := p.NewOptArg('n', "name", "name to use")
+	for _, argv := range [][]string{ { "--format=yaml" }, { "--format", "yaml" }, { "-Fyaml" }, { "-F", "yaml" } } {
+		if err := p.ParseArgv(argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", argv, err)
+		}
+		if format.Opt != "yaml" {
+			t.Fatalf("Expected yaml for %v, got '%s'", argv, format.Opt)
+		}
+	}
+	for _, argv := range [][]string{ { "--format=YAML" }, { "--format", "toml" }, { "-Ftoml" }, { "-F", "x" } } {
+		err := p.ParseArgv(argv)
+		if err == nil {
+			t.Fatalf("Expected error for %v", argv)
+		}
+		if !strings.Contains(err.Error(), "json, yaml, xml") {
+			t.Fatalf("Expected choices listed, got %s", err)
+		}
+	}
+	if err := p.ParseArgv([]string{ "--name", "anything" }); err != nil || name.Opt != "anything" {
+		t.Fatalf("Expected unconstrained value, got '%s', %v", name.Opt, err)
+	}
+}
+
+//Test that validation runs for every form an option can be set in
+func TestValidate(t *testing.T) {
+	p := NewParser()
+	email := regexp.MustCompile(`^[^@]+@[^@]+$`)
+	bad := errors.New("not an address")
+	check := func(s string) error {
+		if !email.MatchString(s) {
+			return bad
+		}
+		return nil
+	}
+	to := p.NewOptArg('t', "to", "recipient")
+	to.Validate = check
+	cc := p.NewOptVec('c', "cc", "copy recipients")
+	cc.Validate = check
+	p.NewOptArg('s', "subject", "subject line")
+	good := [][]string{
+		{ "--to=a@b", "--cc=c@d" },
+		{ "--to", "a@b", "--cc", "c@d" },
+		{ "-ta@b", "-cc@d" },
+		{ "-t", "a@b", "-c", "c@d" },
+	}
+	for _, argv := range good {
+		if err := p.ParseArgv(argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", argv, err)
+		}
+	}
+	rejected := [][]string{
+		{ "--to=nobody" },
+		{ "--to", "nobody" },
+		{ "-tnobody" },
+		{ "--cc=c@d", "--cc=nobody" },
+		{ "-c", "nobody" },
+		{ "-cnobody" },
+	}
+	for _, argv := range rejected {
+		err := p.ParseArgv(argv)
+		if !errors.Is(err, bad) {
+			t.Fatalf("Expected validation error for %v, got %v", argv, err)
+		}
+		if !strings.Contains(err.Error(), "nobody") {
+			t.Fatalf("Expected the value in the error, got %s", err)
+		}
+	}
+	if err := p.ParseArgv([]string{ "-s", "anything at all" }); err != nil {
+		t.Fatalf("Expected no validation without Validate, got %s", err)
+	}
+}
+
+//Test that help is written to HelpWriter, sorted and aligned
+func TestHelpWriter(t *testing.T) {
+	defer func(w io.Writer) { HelpWriter = w }(HelpWriter)
+	var b bytes.Buffer
+	HelpWriter = &b
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+	p.NewFlag('v', "verbose", "print more")
+	p.NewOptArg(0, "output-format", "format of the output")
+	p.NewOptArg('f', "file", "file to read")
+	p.NewFlag(0, "all", "everything")
+	expected := "prog - 1.0\n" +
+		"Does things\n" +
+		"   --all            everything\n" +
+		"-f/--file           file to read\n" +
+		"   --output-format  format of the output\n" +
+		"-v/--verbose        print more\n"
+	for i := 0; i < 3; i++ {
+		b.Reset()
+		p.PrintHelp()
+		if b.String() != expected {
+			t.Fatalf("Expected:\n%s\ngot:\n%s", expected, b.String())
+		}
+	}
+}
+
+//Test the synopsis for a mix of option types
+func TestUsageMixed(t *testing.T) {
+	defer func(w io.Writer) { HelpWriter = w }(HelpWriter)
+	var b bytes.Buffer
+	HelpWriter = &b
+	p := NewParser()
+	p.ProgramName = "/usr/bin/prog"
+	p.NewFlag('a', "all", "everything")
+	p.NewOptArg('f', "file", "file to read")
+	p.NewFlag(0, "verbose", "print more")
+	p.NewOptVec('I', "include", "include directory")
+	p.NewOptCount('d', "debug", "debug level")
+	p.NewOptInt(0, "port", "port to listen on")
+	p.NewOptArg('c', "color", "when to color").ArgOptional = true
+	expected := "usage: prog [-a] [-c[COLOR]] [-d] [-f FILE] [-I INCLUDE] [--port PORT] [--verbose] [args...]\n"
+	p.PrintUsage()
+	if b.String() != expected {
+		t.Fatalf("Expected '%s', got '%s'", expected, b.String())
+	}
+}
+
+//Test abbreviated long options
+func TestAllowAbbrev(t *testing.T) {
+	p := NewParser()
+	verbose := p.NewFlag('v', "verbose", "print more")
+	p.NewFlag('V', "version", "print the version")
+	file := p.NewOptArg('f', "file", "file to read")
+	files := p.NewOptVec('F', "files", "more files")
+	color := p.NewOptArg('c', "color", "when to color").AddAlias("colour")
+	if err := p.ParseArgv([]string{ "--verb" }); err == nil {
+		t.Fatal("Expected abbreviations to be off by default")
+	}
+	AllowAbbrev = true
+	defer func() { AllowAbbrev = false }()
+	if err := p.ParseArgv([]string{ "--verb", "--file=a", "--col", "never" }); err != nil {
+		t.Fatal(err)
+	}
+	if !verbose.Passed || file.Opt != "a" || color.Opt != "never" {
+		t.Fatalf("Expected abbreviations to resolve, got %t '%s' '%s'", verbose.Passed, file.Opt, color.Opt)
+	}
+	if len(files.OptArgs) != 0 {
+		t.Fatalf("Expected exact --file to win over --files, got %v", files.OptArgs)
+	}
+	if err := p.ParseArgv([]string{ "--no-verb" }); err != nil || verbose.Passed {
+		t.Fatalf("Expected --no-verb to negate --verbose, got %t, %v", verbose.Passed, err)
+	}
+	err := p.ParseArgv([]string{ "--ver" })
+	if err == nil || err.Error() != `Argument 0 ("--ver"):  Ambiguous long option ver:  --verbose, --version` {
+		t.Fatalf("Expected ambiguity error, got %v", err)
+	}
+	if err := p.ParseArgv([]string{ "--fi=b" }); err == nil {
+		t.Fatal("Expected ambiguity error for --fi")
+	}
+}
+
+//Test splitting OptVec arguments on a separator
+func TestOptVecSeparator(t *testing.T) {
+	p := NewParser()
+	v := p.NewOptVec('I', "include", "include directories")
+	cases := []struct {
+		sep	string
+		argv	[]string
+		want	[]string
+	}{
+		{ "", []string{ "--include", "a,b,c" }, []string{ "a,b,c" } },
+		{ ",", []string{ "--include", "a,b,c" }, []string{ "a", "b", "c" } },
+		{ ",", []string{ "-Ia,b", "--include=c" }, []string{ "a", "b", "c" } },
+		{ ",", []string{ "-I", "a,,b" }, []string{ "a", "", "b" } },
+		{ ",", []string{ "-I", "a,b," }, []string{ "a", "b", "" } },
+		{ ":", []string{ "-I", "a:b,c" }, []string{ "a", "b,c" } },
+	}
+	for _, c := range cases {
+		v.Separator = c.sep
+		v.OptArgs = nil
+		if err := p.ParseArgv(c.argv); err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(v.OptArgs) != fmt.Sprint(c.want) || len(v.OptArgs) != len(c.want) {
+			t.Fatalf("Expected %q for %v with %q, got %q", c.want, c.argv, c.sep, v.OptArgs)
+		}
+	}
+}
+
+//Test the limits on OptVec arguments and OptCount counts
+func TestCountLimits(t *testing.T) {
+	p := NewParser()
+	inc := p.NewOptVec('I', "include", "include directory")
+	inc.MinCount, inc.MaxCount = 1, 3
+	verbose := p.NewOptCount('v', "verbose", "verbosity")
+	verbose.MaxCount = 2
+	cases := []struct {
+		argv	[]string
+		err	string
+	}{
+		{ []string{ "-Ia", "-vv" }, "" },
+		{ []string{ "-Ia", "-Ib", "-Ic", "-vvv", "+v" }, "" },
+		{ []string{ "-v" }, "Option --include has argument count 0, below the minimum of 1" },
+		{ []string{ "-Ia", "-Ib", "-Ic", "-Id" }, "Option --include has argument count 4, above the maximum of 3" },
+		{ []string{ "-Ia", "-vvv" }, "Option --verbose has count 3, above the maximum of 2" },
+	}
+	for _, c := range cases {
+		inc.OptArgs, verbose.Count = nil, 0
+		err := p.ParseArgv(c.argv)
+		if c.err == "" && err != nil {
+			t.Fatalf("Unexpected error for %v: %s", c.argv, err)
+		}
+		if c.err != "" && (err == nil || err.Error() != c.err) {
+			t.Fatalf("Expected '%s' for %v, got %v", c.err, c.argv, err)
+		}
+	}
+}
+
+//Test that negation stops at the floor of an OptCount
+func TestOptCountFloor(t *testing.T) {
+	p := NewParser()
+	v := p.NewOptCount('v', "verbose", "verbosity").SetMinValue(0)
+	q := p.NewOptCount('q', "quiet", "quietness")
+	cases := []struct {
+		argv	[]string
+		count	int64
+	}{
+		{ []string{ "+v" }, 0 },
+		{ []string{ "+v", "-v" }, 1 },
+		{ []string{ "-vv", "+vvv", "-v" }, 1 },
+		{ []string{ "-v", "+v", "+v", "-vv", "+v" }, 1 },
+	}
+	for _, c := range cases {
+		v.Count = 0
+		if err := p.ParseArgv(c.argv); err != nil {
+			t.Fatal(err)
+		}
+		if v.Count != c.count {
+			t.Fatalf("Expected %d for %v, got %d", c.count, c.argv, v.Count)
+		}
+	}
+	if err := p.ParseArgv([]string{ "+qq" }); err != nil || q.Count != -2 {
+		t.Fatalf("Expected no floor without SetMinValue, got %d, %v", q.Count, err)
+	}
+	floor := int64(0)
+	q.Count, q.MinValue = 0, &floor
+	if err := p.ParseArgv([]string{ "+q", "+q" }); err != nil || q.Count != 0 {
+		t.Fatalf("Expected MinValue set directly to be the floor, got %d, %v", q.Count, err)
+	}
+}
+
+//Test setting an OptCount directly, within and outside its bounds
+func TestOptCountExplicit(t *testing.T) {
+	p := NewParser()
+	v := p.NewOptCount('v', "verbose", "verbosity").SetMinValue(-1).SetMaxValue(3)
+	q := p.NewOptCount('q', "quiet", "quietness")
+	if err := p.ParseArgv([]string{ "--verbose=-1", "--quiet=-7" }); err != nil || v.Count != -1 || q.Count != -7 {
+		t.Fatalf("Expected -1 and -7, got %d %d, %v", v.Count, q.Count, err)
+	}
+	if err := p.ParseArgv([]string{ "--verbose=2", "-vvv" }); err != nil || v.Count != 5 {
+		t.Fatalf("Expected passing -v not to be limited by MaxValue, got %d, %v", v.Count, err)
+	}
+	cases := []struct {
+		arg	string
+		msg	string
+	}{
+		{ "--verbose=4", "Invalid value 4 for --verbose, expected at most 3" },
+		{ "--verbose=-2", "Invalid value -2 for --verbose, expected at least -1" },
+		{ "--verbose=lots", "Invalid value lots for --verbose, expected an integer" },
+	}
+	for _, c := range cases {
+		v.Count = 1
+		err := p.ParseArgv([]string{ c.arg })
+		var value *ParseValueError
+		if err == nil || err.Error() != fmt.Sprintf("Argument 0 (%q):  %s", c.arg, c.msg) || !errors.As(err, &value) || value.Long != "verbose" {
+			t.Fatalf("Expected %q for %s, got %v", c.msg, c.arg, err)
+		}
+		if v.Count != 1 {
+			t.Fatalf("Expected %s to leave the count alone, got %d", c.arg, v.Count)
+		}
+	}
+}
+
+//Test that OnSet functions are called in argument order, and that
+//an error from one ends the parse
+func TestOnSet(t *testing.T) {
+	p := NewParser()
+	calls := make([]string, 0, initialCapacity)
+	record := func(name string) func(string) error {
+		return func(value string) error {
+			calls = append(calls, name + "=" + value)
+			return nil
+		}
+	}
+	q := p.NewFlag('q', "quiet", "print less")
+	q.OnSet = record("q")
+	inc := p.NewOptVec('I', "include", "include directory")
+	inc.OnSet = record("I")
+	v := p.NewOptCount('v', "verbose", "verbosity")
+	v.OnSet = record("v")
+	port := p.NewOptInt('p', "port", "port to listen on")
+	port.OnSet = record("p")
+	argv := []string{ "-Ia", "-q", "--include", "b", "-vIc", "+q", "--port=80", "-vp", "81", "--no-quiet" }
+	if err := p.ParseArgv(argv); err != nil {
+		t.Fatal(err)
+	}
+	expected := "[I=a q= I=b v= I=c q= p=80 v= p=81 q=]"
+	if fmt.Sprint(calls) != expected {
+		t.Fatalf("Expected %s, got %v", expected, calls)
+	}
+
+	stop := errors.New("stop")
+	inc.OnSet = func(value string) error {
+		if value == "bad" {
+			return stop
+		}
+		return nil
+	}
+	calls = calls[:0]
+	if err := p.ParseArgv([]string{ "-q", "-I", "bad", "-q" }); err != stop {
+		t.Fatalf("Expected the OnSet error, got %v", err)
+	}
+	if len(calls) != 1 {
+		t.Fatalf("Expected parsing to stop at the error, got %v", calls)
+	}
+}
+
+//Test that nothing after "--" is taken as an option or for stdin
+func TestTerminatorVerbatim(t *testing.T) {
+	defer func(h func() error) { StdinHandler = h }(StdinHandler)
+	stdin := false
+	StdinHandler = func() error {
+		stdin = true
+		return nil
+	}
+	p := NewParser()
+	x := p.NewFlag('x', "extra", "extra output")
+	foo := p.NewFlag(0, "foo", "foo")
+	x.Passed = true
+	after := []string{ "-", "--foo", "+x", "--", "-x", "@args", "-5" }
+	if err := p.ParseArgv(append([]string{ "--" }, after...)); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(p.Rest) != fmt.Sprint(after) || len(p.Rest) != len(after) {
+		t.Fatalf("Expected %v in Rest, got %v", after, p.Rest)
+	}
+	if stdin || foo.Passed || !x.Passed {
+		t.Fatal("Expected nothing after -- to be parsed")
+	}
+}
+
+//Test that an omitted optional argument leaves the next word alone
+//in every position
+func TestArgOptionalNextWord(t *testing.T) {
+	p := NewParser()
+	c := p.NewOptArgOptional('c', "color", "when to use color", "auto")
+	v := p.NewFlag('v', "verbose", "print more")
+	values := make([]string, 0, initialCapacity)
+	c.OnSet = func(value string) error {
+		values = append(values, value)
+		return nil
+	}
+	if err := p.ParseArgv([]string{ "-vc", "always", "--color", "-v", "-c", "never", "-cnever" }); err != nil {
+		t.Fatal(err)
+	}
+	if c.Opt != "never" || !v.Passed {
+		t.Fatalf("Expected never and -v, got '%s' %t", c.Opt, v.Passed)
+	}
+	if fmt.Sprint(p.Rest) != "[always never]" {
+		t.Fatalf("Expected [always never] in Rest, got %v", p.Rest)
+	}
+	if fmt.Sprint(values) != "[   never]" {
+		t.Fatalf("Expected OnSet without a value but for -cnever, got %q", values)
+	}
+	if err := p.ParseArgv([]string{ "--color" }); err != nil || c.Opt != "auto" {
+		t.Fatalf("Expected auto at the end of the arguments, got '%s', %v", c.Opt, err)
+	}
+}
+
+//Test matching long options regardless of case
+func TestCaseInsensitiveLong(t *testing.T) {
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	f := p.NewOptArg('f', "file", "file to read")
+	p.NewOptVec('F', "files", "more files")
+	if err := p.ParseArgv([]string{ "--Verbose" }); err == nil {
+		t.Fatal("Expected case to matter by default")
+	}
+	CaseInsensitiveLong = true
+	defer func() { CaseInsensitiveLong = false }()
+	if err := p.ParseArgv([]string{ "--VERBOSE", "--File=a" }); err != nil || !v.Passed || f.Opt != "a" {
+		t.Fatalf("Expected --VERBOSE and --File to match, got %t '%s', %v", v.Passed, f.Opt, err)
+	}
+	if err := p.ParseArgv([]string{ "--No-Verbose", "--FILE", "b" }); err != nil || v.Passed || f.Opt != "b" {
+		t.Fatalf("Expected --No-Verbose and --FILE to match, got %t '%s', %v", v.Passed, f.Opt, err)
+	}
+	AllowAbbrev = true
+	defer func() { AllowAbbrev = false }()
+	if err := p.ParseArgv([]string{ "--VERB", "--FILE", "c" }); err != nil || !v.Passed || f.Opt != "c" {
+		t.Fatalf("Expected --VERB and --FILE to match, got %t '%s', %v", v.Passed, f.Opt, err)
+	}
+	defer func() {
+		if r := recover(); r != "Long option --Verbose differs from --verbose only in case" {
+			t.Fatalf("Expected panic for a name differing in case, got %v", r)
+		}
+	}()
+	p.NewFlag('x', "Verbose", "another verbose")
+}
+
+//Test single dash long options
+func TestSingleDashLong(t *testing.T) {
+	p := NewParser()
+	verbose := p.NewFlag(0, "verbose", "print more")
+	name := p.NewOptArg(0, "name", "name to find")
+	out := p.NewOptArg('o', "output", "output file")
+	a := p.NewFlag('a', "all", "everything")
+	q := p.NewFlag('q', "quiet", "print less")
+	if err := p.ParseArgv([]string{ "-verbose" }); err == nil {
+		t.Fatal("Expected -verbose to be short options by default")
+	}
+	SingleDashLong = true
+	defer func() { SingleDashLong = false }()
+	if err := p.ParseArgv([]string{ "-verbose", "-name", "*.go", "-aq" }); err != nil {
+		t.Fatal(err)
+	}
+	if !verbose.Passed || name.Opt != "*.go" || !a.Passed || !q.Passed {
+		t.Fatalf("Expected -verbose, -name and -aq, got %t '%s' %t %t", verbose.Passed, name.Opt, a.Passed, q.Passed)
+	}
+	if err := p.ParseArgv([]string{ "-name=x", "-output" }); err != nil || name.Opt != "x" || out.Opt != "utput" {
+		t.Fatalf("Expected -name=x, and -output as a short option with a value, got '%s' '%s', %v", name.Opt, out.Opt, err)
+	}
+	if err := p.ParseArgv([]string{ "-allx" }); err == nil {
+		t.Fatal("Expected -allx to be an unknown long option")
+	}
+}
+
+//Test that OptInd points just past the last option taken
+func TestOptInd(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('v', "verbose", "print more")
+	p.NewOptArg('f', "file", "file to read")
+	cases := []struct {
+		argv	[]string
+		ind	int
+	}{
+		{ []string{}, 0 },
+		{ []string{ "build", "-v" }, 2 },
+		{ []string{ "-v", "build", "x" }, 1 },
+		{ []string{ "-f", "a", "build" }, 2 },
+		{ []string{ "--file=a", "", "build" }, 1 },
+		{ []string{ "-v", "--", "-f", "x" }, 2 },
+		{ []string{ "build", "--", "-v" }, 2 },
+		{ []string{ "-v", "-", "-5" }, 2 },
+	}
+	for _, c := range cases {
+		if err := p.ParseArgv(c.argv); err != nil {
+			t.Fatalf("%v: %s", c.argv, err)
+		}
+		if p.OptInd != c.ind {
+			t.Fatalf("%v: expected OptInd %d, got %d", c.argv, c.ind, p.OptInd)
+		}
+	}
+	Reset()
+	NewFlag('v', "verbose", "print more")
+	if err := ParseArgv([]string{ "-v", "run", "x" }); err != nil || OptInd != 1 {
+		t.Fatalf("Expected OptInd 1 after -v, got %d, %v", OptInd, err)
+	}
+}
+
+//Test that options are grouped under their categories in the help
+func TestHelpCategories(t *testing.T) {
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+	p.NewOptInt('p', "port", "port to listen on").Category = "Network"
+	p.NewFlag('v', "verbose", "print more")
+	p.NewOptArg('o', "output", "output file").Category = "Output"
+	p.NewOptArg(0, "host", "host to bind").Category = "Network"
+	p.NewFlag('q', "quiet", "print less").Category = "Output"
+	expected := "prog - 1.0\n" +
+		"Does things\n" +
+		"-v/--verbose  print more\n" +
+		"\n" +
+		"Network:\n" +
+		"   --host     host to bind\n" +
+		"-p/--port     port to listen on\n" +
+		"\n" +
+		"Output:\n" +
+		"-o/--output   output file\n" +
+		"-q/--quiet    print less\n"
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+//Test that a long option with no name is an error
+func TestEmptyLongName(t *testing.T) {
+	p := NewParser()
+	p.NewOptArg('f', "file", "file to read")
+	defer func() { AllowAbbrev = false }()
+	for _, abbrev := range []bool{ false, true } {
+		AllowAbbrev = abbrev
+		for _, arg := range []string{ "--=value", "--=" } {
+			err := p.ParseArgv([]string{ arg, "x" })
+			if err == nil || err.Error() != fmt.Sprintf("Argument 0 (%q):  Empty long option name", arg) {
+				t.Fatalf("Expected an empty long option name error for %s, got %v", arg, err)
+			}
+		}
+	}
+}
+
+//Test "--" where an option is waiting for its argument
+func TestTerminatorEndsOptArg(t *testing.T) {
+	p := NewParser()
+	f := p.NewOptArg('f', "file", "file to read")
+	n := p.NewOptInt('n', "count", "how many")
+	if err := p.ParseArgv([]string{ "-f", "--" }); err != nil || f.Opt != "--" || len(p.Rest) != 0 {
+		t.Fatalf("Expected a final -- as the argument by default, got '%s' %v, %v", f.Opt, p.Rest, err)
+	}
+	TerminatorEndsOptArg = true
+	defer func() { TerminatorEndsOptArg = false }()
+	cases := []struct {
+		argv	[]string
+		long	string
+	}{
+		{ []string{ "-f", "--", "-n", "1" }, "file" },
+		{ []string{ "--count", "--", "-n", "1" }, "count" },
+	}
+	for _, c := range cases {
+		f.Opt, n.Value = "", 0
+		err := p.ParseArgv(c.argv)
+		var missing *MissingArgError
+		if !errors.As(err, &missing) || missing.Long != c.long {
+			t.Fatalf("Expected a missing argument for --%s, got %v", c.long, err)
+		}
+		if f.Opt != "" || n.Value != 0 || fmt.Sprint(p.Rest) != "[-n 1]" {
+			t.Fatalf("Expected -- to end the options for %v, got '%s' %d %v", c.argv, f.Opt, n.Value, p.Rest)
+		}
+	}
+	if err := p.ParseArgv([]string{ "-f--" }); err != nil || f.Opt != "--" {
+		t.Fatalf("Expected -f-- to still take --, got '%s', %v", f.Opt, err)
+	}
+}
+
+//Test that short options must be passed separately without clumping
+func TestDisableClumping(t *testing.T) {
+	p := NewParser()
+	a := p.NewFlag('a', "all", "everything")
+	b := p.NewFlag('b', "brief", "less")
+	c := p.NewOptCount('c', "count", "how many")
+	f := p.NewOptArg('f', "file", "file to read")
+	AllowClumping = false
+	defer func() { AllowClumping = true }()
+	for _, argv := range [][]string{ { "-abc" }, { "-ab" }, { "-af", "x" }, { "+ab" } } {
+		var clumped *ClumpedShortsError
+		if err := p.ParseArgv(argv); !errors.As(err, &clumped) || clumped.Arg != argv[0] || !errors.As(err, new(*ArgError)) {
+			t.Fatalf("Expected an error for %v, got %v", argv, err)
+		}
+	}
+	for _, argv := range [][]string{ { "-xa" }, { "+xa" } } {
+		var unknown *UnknownOptionError
+		if err := p.ParseArgv(argv); !errors.As(err, &unknown) || unknown.Name != "x" || !errors.As(err, new(*ArgError)) {
+			t.Fatalf("Expected -x to be unknown for %v, got %v", argv, err)
+		}
+	}
+	CollectErrors = true
+	err := p.ParseArgv([]string{ "-ab", "-xa", "+ab" })
+	CollectErrors = false
+	expected := `Argument 0 ("-ab"):  Short options must be passed separately:  -ab` + "\n" +
+		`Argument 1 ("-xa"):  Unrecognized short option:  'x'` + "\n" +
+		`Argument 2 ("+ab"):  Short options must be passed separately:  +ab`
+	if err == nil || err.Error() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%v", expected, err)
+	}
+	defer func() { UnknownHandler = nil }()
+	handled := make([]string, 0, initialCapacity)
+	UnknownHandler = func(arg string) error { handled = append(handled, arg); return nil }
+	if err := p.ParseArgv([]string{ "-xa", "+xa" }); err != nil || fmt.Sprint(handled) != "[-xa +xa]" {
+		t.Fatalf("Expected both groups handled, got %v, %v", handled, err)
+	}
+	UnknownHandler = nil
+	if err := p.ParseArgv([]string{ "-a", "-b", "-c", "-fvalue", "+c" }); err != nil {
+		t.Fatal(err)
+	}
+	if !a.Passed || !b.Passed || c.Count != 0 || f.Opt != "value" {
+		t.Fatalf("Expected -a -b -c -fvalue +c, got %t %t %d '%s'", a.Passed, b.Passed, c.Count, f.Opt)
+	}
+	if err := p.ParseArgv([]string{ "-fab" }); err != nil || f.Opt != "ab" {
+		t.Fatalf("Expected -fab to attach ab, got '%s', %v", f.Opt, err)
+	}
+}
+
+//Test options with no short name, parsed by their long names and
+//shown without a short name anywhere
+func TestLongOnly(t *testing.T) {
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+	dry := p.NewFlag(0, "dry-run", "change nothing")
+	out := p.NewOptArg(0, "output", "output file")
+	v := p.NewFlag('v', "verbose", "print more")
+	if _, ok := p.optByShort[0]; ok {
+		t.Fatal("Expected no short option 0")
+	}
+	if err := p.ParseArgv([]string{ "--dry-run", "--output", "out.txt", "-v" }); err != nil {
+		t.Fatal(err)
+	}
+	if !dry.Passed || out.Opt != "out.txt" || !v.Passed {
+		t.Fatalf("Expected --dry-run --output out.txt -v, got %t '%s' %t", dry.Passed, out.Opt, v.Passed)
+	}
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	help := "prog - 1.0\n" +
+		"Does things\n" +
+		"   --dry-run  change nothing\n" +
+		"   --output   output file\n" +
+		"-v/--verbose  print more\n"
+	if b.String() != help {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", help, b.String())
+	}
+	if u := p.Usage(); u != "usage: prog [--dry-run] [--output OUTPUT] [-v] [args...]" {
+		t.Fatalf("Unexpected usage %q", u)
+	}
+	err := p.ParseArgv([]string{ "--output" })
+	if err == nil || err.Error() != "Expecting argument for option:  --output" {
+		t.Fatalf("Expected a missing argument for --output, got %v", err)
+	}
+	for _, s := range []string{ b.String(), p.Usage(), err.Error(), p.GetoptString() } {
+		if strings.IndexByte(s, 0) != -1 {
+			t.Fatalf("Unexpected NUL in %q", s)
+		}
+	}
+}
+
+//Test the bounds on the number of arguments in Rest
+func TestArgCount(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('v', "verbose", "print more")
+	MinArgs, MaxArgs = 1, 2
+	defer func() { MinArgs, MaxArgs = 0, -1 }()
+	cases := []struct {
+		argv	[]string
+		msg	string
+	}{
+		{ []string{ "-v" }, "Expected at least 1 arguments, got 0" },
+		{ []string{ "a", "-v", "b", "c", "d" }, "Expected at most 2 arguments, got 4" },
+		{ []string{ "a", "--", "b", "-v" }, "Expected at most 2 arguments, got 3" },
+		{ []string{ "-v", "--", "-a" }, "" },
+		{ []string{ "a", "-v", "b" }, "" },
+	}
+	for _, c := range cases {
+		err := p.ParseArgv(c.argv)
+		if c.msg == "" && err != nil {
+			t.Fatalf("Expected no error for %v, got %s", c.argv, err)
+		}
+		if c.msg != "" && (err == nil || err.Error() != c.msg || !errors.As(err, new(*UsageError))) {
+			t.Fatalf("Expected %q for %v, got %v", c.msg, c.argv, err)
+		}
+	}
+	MaxArgs = 0
+	if err := p.ParseMap(map[string]string{ "verbose": "true" }); err != nil || len(p.Rest) != 0 || p.OptInd != 0 {
+		t.Fatalf("Expected ParseMap to leave the bounds unchecked and Rest empty, got %v %d, %v", p.Rest, p.OptInd, err)
+	}
+}
+
+//Test that an option's argument may start with a dash, and that
+//"--" escapes the argument after it
+func TestDashArgument(t *testing.T) {
+	p := NewParser()
+	pattern := p.NewOptArg('e', "pattern", "pattern to match")
+	n := p.NewOptInt('n', "count", "how many")
+	inc := p.NewOptVec('I', "include", "include directory")
+	cases := []struct {
+		argv	[]string
+		value	string
+		rest	string
+	}{
+		{ []string{ "--pattern", "-foo", "x" }, "-foo", "[x]" },
+		{ []string{ "-e", "--foo" }, "--foo", "[]" },
+		{ []string{ "--pattern", "--", "-foo", "x" }, "-foo", "[x]" },
+		{ []string{ "-e", "--", "--", "--", "x" }, "--", "[x]" },
+		{ []string{ "-e", "--", "--" }, "--", "[]" },
+	}
+	for _, c := range cases {
+		if err := p.ParseArgv(c.argv); err != nil {
+			t.Fatalf("Unexpected error for %v: %s", c.argv, err)
+		}
+		if pattern.Opt != c.value || fmt.Sprint(p.Rest) != c.rest {
+			t.Fatalf("Expected '%s' and %s for %v, got '%s' %v", c.value, c.rest, c.argv, pattern.Opt, p.Rest)
+		}
+	}
+	if err := p.ParseArgv([]string{ "-n", "--", "-5", "-I", "--", "-x" }); err != nil || n.Value != -5 || fmt.Sprint(inc.OptArgs) != "[-x]" {
+		t.Fatalf("Expected -5 and [-x], got %d %v, %v", n.Value, inc.OptArgs, err)
+	}
+}
+
+//Test that the "--" escape leaves "--" as the value before anything
+//not like an option, and never applies to a greedy OptVec
+func TestTerminatorEscapeGreedy(t *testing.T) {
+	p := NewParser()
+	f := p.NewOptArg('f', "file", "file to read")
+	cmd := p.NewOptVec('c', "cmd", "command to run")
+	cmd.Greedy = true
+	v := p.NewFlag('v', "verbose", "print more")
+	cases := []struct {
+		argv	[]string
+		file	string
+		args	string
+		rest	string
+	}{
+		{ []string{ "-f", "--", "x" }, "--", "[]", "[x]" },
+		{ []string{ "-f", "--", "-x", "y" }, "-x", "[]", "[y]" },
+		{ []string{ "--cmd", "a", "b", "--", "c" }, "", "[a b]", "[c]" },
+		{ []string{ "-f", "--", "--cmd", "a", "--", "-v" }, "--cmd", "[]", "[a -v]" },
+		{ []string{ "-f", "--", "x", "--cmd", "a", "--", "-v" }, "--", "[a]", "[x -v]" },
+	}
+	for _, c := range cases {
+		f.Opt, cmd.OptArgs = "", nil
+		if err := p.ParseArgv(c.argv); err != nil || v.Passed {
+			t.Fatalf("Unexpected error or -v for %v: %v", c.argv, err)
+		}
+		if f.Opt != c.file || fmt.Sprint(cmd.OptArgs) != c.args || fmt.Sprint(p.Rest) != c.rest {
+			t.Fatalf("Expected '%s' %s %s for %v, got '%s' %v %v", c.file, c.args, c.rest, c.argv, f.Opt, cmd.OptArgs, p.Rest)
+		}
+	}
+	cmd.OptArgs = nil
+	err := p.ParseArgv([]string{ "--cmd", "--", "a", "b" })
+	var missing *MissingArgError
+	if !errors.As(err, &missing) || missing.Long != "cmd" || len(cmd.OptArgs) != 0 || fmt.Sprint(p.Rest) != "[a b]" {
+		t.Fatalf("Expected -- to end --cmd with [a b] in Rest, got %v %v, %v", cmd.OptArgs, p.Rest, err)
+	}
+}
+
+//Test that hidden options are parsed but left out of the help
+func TestHiddenOption(t *testing.T) {
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+	p.NewFlag('v', "verbose", "print more")
+	prof := p.NewOptArg(0, "profile-cpu", "write a CPU profile")
+	prof.Hidden = true
+	trace := p.NewFlag('T', "trace", "trace everything")
+	trace.Hidden = true
+	p.NewExclusiveGroup("debug", false, prof, trace)
+	if err := p.ParseArgv([]string{ "--profile-cpu", "cpu.out" }); err != nil || prof.Opt != "cpu.out" {
+		t.Fatalf("Expected --profile-cpu to parse, got '%s', %v", prof.Opt, err)
+	}
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	if b.String() != "prog - 1.0\nDoes things\n-v/--verbose  print more\n" {
+		t.Fatalf("Expected only --verbose in help, got %q", b.String())
+	}
+	if u := p.Usage(); u != "usage: prog [-v] [args...]" {
+		t.Fatalf("Expected only -v in usage, got %q", u)
+	}
+}
+
+//Test that UnknownHandler is given unrecognized options in place of errors
+func TestUnknownHandler(t *testing.T) {
+	unknown := make([]string, 0, initialCapacity)
+	UnknownHandler = func(arg string) error {
+		unknown = append(unknown, arg)
+		return nil
+	}
+	defer func() { UnknownHandler = nil }()
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	f := p.NewOptArg('f', "file", "file to read")
+	argv := []string{ "--bogus=1", "-vx", "--nope", "-y", "-f", "a", "--", "--other" }
+	if err := p.ParseArgv(argv); err != nil {
+		t.Fatalf("Expected the parse to succeed, got %v", err)
+	}
+	if fmt.Sprint(unknown) != "[--bogus=1 -vx --nope -y]" {
+		t.Fatalf("Expected the unknown options to be collected, got %v", unknown)
+	}
+	if v.Passed || f.Opt != "a" || fmt.Sprint(p.Rest) != "[--other]" {
+		t.Fatalf("Expected only --file set, got %t '%s' %v", v.Passed, f.Opt, p.Rest)
+	}
+	UnknownHandler = func(arg string) error {
+		return fmt.Errorf("Refusing %s", arg)
+	}
+	if err := p.ParseArgv([]string{ "-v", "--bogus" }); err == nil || err.Error() != "Refusing --bogus" {
+		t.Fatalf("Expected the handler's error, got %v", err)
+	}
+}
+
+//Test that StrictOptArg keeps a waiting option from taking an option
+func TestStrictOptArg(t *testing.T) {
+	p := NewParser()
+	f := p.NewOptArg('f', "file", "file to read")
+	v := p.NewFlag('v', "verbose", "print more")
+	if err := p.ParseArgv([]string{ "--file", "--verbose" }); err != nil || f.Opt != "--verbose" || v.Passed {
+		t.Fatalf("Expected --verbose as the file by default, got '%s' %t, %v", f.Opt, v.Passed, err)
+	}
+	StrictOptArg = true
+	defer func() { StrictOptArg = false }()
+	f.Opt = ""
+	err := p.ParseArgv([]string{ "--file", "--verbose" })
+	var missing *MissingArgError
+	if !errors.As(err, &missing) || missing.Long != "file" {
+		t.Fatalf("Expected --file to be missing its argument, got %v", err)
+	}
+	if err := p.ParseArgv([]string{ "--file=--verbose", "-f", "--", "-v" }); err != nil || f.Opt != "-v" || v.Passed {
+		t.Fatalf("Expected the attached and escaped forms to be taken, got '%s' %t, %v", f.Opt, v.Passed, err)
+	}
+	if err := p.ParseArgv([]string{ "-f", "-v" }); !errors.As(err, &missing) || missing.Short != 'f' {
+		t.Fatalf("Expected -f to be missing its argument, got %v", err)
+	}
+	offset := p.NewOptVec('o', "offset", "offsets to apply")
+	if err := p.ParseArgv([]string{ "-f", "-5", "--offset", "-2.5" }); err != nil || f.Opt != "-5" || fmt.Sprint(offset.OptArgs) != "[-2.5]" {
+		t.Fatalf("Expected the negative numbers to be taken, got '%s' %v, %v", f.Opt, offset.OptArgs, err)
+	}
+}
+
+//Test that SetProgramInfo drives the help, which leaves out an empty
+//description
+func TestSetProgramInfo(t *testing.T) {
+	Reset()
+	defer Reset()
+	NewFlag('v', "verbose", "print more")
+	SetProgramInfo("prog", "1.0", "Does things")
+	if ProgramName != "prog" || ProgramVersion != "1.0" || ProgramDesc != "Does things" {
+		t.Fatalf("Expected the program info to be set, got %s %s %s", ProgramName, ProgramVersion, ProgramDesc)
+	}
+	loadDefault()
+	var b bytes.Buffer
+	defaultParser.writeHelp(&b)
+	if b.String() != "prog - 1.0\nDoes things\n-v/--verbose  print more\n" {
+		t.Fatalf("Expected help with the description, got %q", b.String())
+	}
+	SetProgramInfo("prog", "1.0", "")
+	loadDefault()
+	b.Reset()
+	defaultParser.writeHelp(&b)
+	if b.String() != "prog - 1.0\n-v/--verbose  print more\n" {
+		t.Fatalf("Expected help without a blank description, got %q", b.String())
+	}
+}
+
+//Test that setting an OptCount replaces the count so far, and later
+//passes count on from it, whatever the order
+func TestOptCountOrdering(t *testing.T) {
+	cases := []struct {
+		argv	[]string
+		count	int64
+	}{
+		{ []string{ "--verbose", "--verbose" }, 2 },
+		{ []string{ "--verbose=3", "--verbose" }, 4 },
+		{ []string{ "--verbose", "--verbose=3" }, 3 },
+		{ []string{ "-vv", "--verbose=1", "-v" }, 2 },
+		{ []string{ "--verbose=3", "+v" }, 2 },
+		{ []string{ "+v", "--verbose=3" }, 3 },
+		{ []string{ "--verbose=2", "--verbose=5" }, 5 },
+		{ []string{ "-vvv", "--verbose=0" }, 0 },
+		{ []string{ "--verbose=0", "-vvv" }, 3 },
+	}
+	for _, c := range cases {
+		p := NewParser()
+		v := p.NewOptCount('v', "verbose", "print more")
+		if err := p.ParseArgv(c.argv); err != nil || v.Count != c.count {
+			t.Fatalf("Expected %d for %v, got %d, %v", c.count, c.argv, v.Count, err)
+		}
+	}
+}
+
+//Test that Validate calls none of the handlers, and keeps the group
+//violations of the last parse
+func TestValidateHandlers(t *testing.T) {
+	defer func(h func() error) {
+		StdinHandler, StdinReaderHandler, UnknownHandler, PositionalHandler = h, nil, nil, nil
+	}(StdinHandler)
+	calls := make([]string, 0, initialCapacity)
+	StdinHandler = func() error { calls = append(calls, "stdin"); return nil }
+	UnknownHandler = func(arg string) error { calls = append(calls, arg); return nil }
+	PositionalHandler = func(arg string) error { calls = append(calls, arg); return nil }
+	p := NewParser()
+	a := p.NewFlag('a', "all", "everything")
+	b := p.NewFlag('b', "brief", "a summary")
+	p.NewExclusiveGroup("mode", false, a, b)
+	if err := p.ParseArgv([]string{ "-a", "-b" }); err == nil || len(p.GroupViolations()) != 1 {
+		t.Fatalf("Expected a group violation, got %v, %v", p.GroupViolations(), err)
+	}
+	if err := p.Validate([]string{ "-", "a", "--bogus", "-a" }); err != nil {
+		t.Fatalf("Expected the arguments to validate, got %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("Expected no handler to be called, got %v", calls)
+	}
+	if len(p.GroupViolations()) != 1 {
+		t.Fatalf("Expected the last parse's violation kept, got %v", p.GroupViolations())
+	}
+	checks := 0
+	a.Check = func(*Parser) error { checks++; return nil }
+	if err := p.Validate([]string{ "-a" }); err != nil || checks != 0 || a.Check == nil {
+		t.Fatalf("Expected Check not to be called but kept, got %d calls, %v", checks, err)
+	}
+}
+
+//Test that Validate reports errors without changing any option
+func TestValidateArgv(t *testing.T) {
+	p := NewParser()
+	calls := 0
+	v := p.NewFlag('v', "verbose", "print more")
+	v.OnSet = func(string) error { calls++; return nil }
+	f := p.NewOptArg('f', "file", "file to read")
+	inc := p.NewOptVec('I', "include", "include directory")
+	n := p.NewOptCount('n', "level", "level")
+	port := p.NewOptInt('p', "port", "port to listen on")
+	var bound struct {
+		Output	string	`getopt:",output"`
+	}
+	if err := p.Bind(&bound); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseArgv([]string{ "-f", "a", "-I", "x", "rest" }); err != nil {
+		t.Fatal(err)
+	}
+	for _, argv := range [][]string{
+		{ "-v", "--file", "b", "-I", "y", "-nn", "--port", "80", "--output", "o", "z" },
+		{ "-v", "--port=http" },
+	} {
+		err := p.Validate(argv)
+		if (argv[1] == "--port=http") != (err != nil) {
+			t.Fatalf("Expected Validate of %v to agree with ParseArgv, got %v", argv, err)
+		}
+		if v.Passed || v.OnSet == nil || f.Opt != "a" || !f.Seen || fmt.Sprint(inc.OptArgs) != "[x]" || n.Count != 0 || port.Value != 0 || port.Seen {
+			t.Fatalf("Expected the options unchanged by %v, got %t '%s' %v %d %d", argv, v.Passed, f.Opt, inc.OptArgs, n.Count, port.Value)
+		}
+		if fmt.Sprint(p.Rest) != "[rest]" || p.OptInd != 4 || bound.Output != "" || calls != 0 {
+			t.Fatalf("Expected the parse results unchanged by %v, got %v %d '%s' %d", argv, p.Rest, p.OptInd, bound.Output, calls)
+		}
+	}
+}
+
+//Test that only the first "--" ends the options, and every argument
+//after it, including more "--", goes to Rest unchanged
+func TestMultipleTerminators(t *testing.T) {
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	f := p.NewOptArg('f', "file", "file to read")
+	cases := []struct {
+		argv	[]string
+		rest	[]string
+	}{
+		{ []string{ "--", "--" }, []string{ "--" } },
+		{ []string{ "a", "--", "-v", "--", "--file", "--" }, []string{ "a", "-v", "--", "--file", "--" } },
+		{ []string{ "-f", "x", "--", "--", "-v" }, []string{ "--", "-v" } },
+		{ []string{ "-f", "--", "--", "--", "-v" }, []string{ "-v" } },
+	}
+	for _, c := range cases {
+		v.Passed = false
+		if err := p.ParseArgv(c.argv); err != nil || v.Passed || fmt.Sprint(p.Rest) != fmt.Sprint(c.rest) {
+			t.Fatalf("Expected %v in Rest for %v, got %v %t, %v", c.rest, c.argv, p.Rest, v.Passed, err)
+		}
+	}
+	if f.Opt != "--" {
+		t.Fatalf("Expected the escaped -- as the file, got '%s'", f.Opt)
+	}
+
+	CollectErrors, StrictOptArg = true, true
+	defer func() { CollectErrors, StrictOptArg = false, false }()
+	err := p.ParseArgv([]string{ "--bogus", "--", "--bogus", "--", "-v" })
+	if err == nil || fmt.Sprint(p.Rest) != "[--bogus -- -v]" || v.Passed {
+		t.Fatalf("Expected one error and the rest unchanged, got %v %t, %v", p.Rest, v.Passed, err)
+	}
+
+	q := NewParser()
+	var rest []string
+	cmd := q.AddCommand("run", "run it", func(argv []string) error { rest = argv; return nil })
+	cmd.NewFlag('v', "verbose", "print more")
+	if err := q.Dispatch([]string{ "run", "--", "-v", "--" }); err != nil || fmt.Sprint(rest) != "[-v --]" {
+		t.Fatalf("Expected [-v --] for the command, got %v, %v", rest, err)
+	}
+}
+
+//Test that HelpString gives each option, and what PrintHelp prints
+func TestHelpString(t *testing.T) {
+	defer func(w io.Writer) { HelpWriter = w }(HelpWriter)
+	var b bytes.Buffer
+	HelpWriter = &b
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion, p.ProgramDesc = "prog", "1.0", "Does things"
+	p.NewFlag('v', "verbose", "print more")
+	p.NewOptArg('f', "file", "file to read")
+	p.NewOptCount(0, "level", "how much")
+	help := p.HelpString()
+	for _, s := range []string{ "prog - 1.0", "Does things", "-v/--verbose", "-f/--file", "--level" } {
+		if !strings.Contains(help, s) {
+			t.Fatalf("Expected %s in the help, got %q", s, help)
+		}
+	}
+	p.PrintHelp()
+	if b.String() != help {
+		t.Fatalf("Expected PrintHelp to print %q, got %q", help, b.String())
+	}
+}
+
+//Test that a number attached to an OptCount in a group sets the count
+func TestAttachedCount(t *testing.T) {
+	p := NewParser()
+	v := p.NewOptCount('v', "verbose", "print more")
+	q := p.NewFlag('q', "quiet", "print less")
+	cases := []struct {
+		argv	[]string
+		count	int64
+	}{
+		{ []string{ "-v3" }, 3 },
+		{ []string{ "-vv" }, 2 },
+		{ []string{ "-qv0x10" }, 16 },
+		{ []string{ "-v3", "-v" }, 4 },
+		{ []string{ "-vvq" }, 2 },
+	}
+	for _, c := range cases {
+		v.Count = 0
+		if err := p.ParseArgv(c.argv); err != nil || v.Count != c.count {
+			t.Fatalf("Expected %d for %v, got %d, %v", c.count, c.argv, v.Count, err)
+		}
+	}
+	if !q.Passed {
+		t.Fatal("Expected -q to be set")
+	}
+	var unknown *UnknownOptionError
+	if err := p.ParseArgv([]string{ "-v3x" }); !errors.As(err, &unknown) || unknown.Name != "3" {
+		t.Fatalf("Expected 3 to be unrecognized in -v3x, got %v", err)
+	}
+	v.SetMaxValue(5)
+	if err := p.ParseArgv([]string{ "-v9" }); err == nil {
+		t.Fatal("Expected an error for a count above the maximum")
+	}
+	AllowClumping = false
+	defer func() { AllowClumping = true }()
+	v.Count = 0
+	if err := p.ParseArgv([]string{ "-v3" }); err != nil || v.Count != 3 {
+		t.Fatalf("Expected -v3 to be allowed without clumping, got %d, %v", v.Count, err)
+	}
+}
+
+//Test that negating an OptVec restores a copy of its default, which
+//later arguments replace without changing the default
+func TestOptVecNegationDefault(t *testing.T) {
+	p := NewParser()
+	inc := p.NewOptVec('I', "include", "include directory").SetDefault([]string{ "/usr/include", "/opt/include" })
+	p.NewFlag('v', "verbose", "print more")
+	cases := []struct {
+		argv	[]string
+		args	string
+	}{
+		{ []string{ "-I", "a", "+I" }, "[/usr/include /opt/include]" },
+		{ []string{ "-I", "a", "+vI" }, "[/usr/include /opt/include]" },
+		{ []string{ "-I", "a", "+I", "-I", "b", "-Ic" }, "[b c]" },
+		{ []string{ "+I", "-I", "d" }, "[d]" },
+	}
+	for _, c := range cases {
+		if err := p.ParseArgv(c.argv); err != nil || fmt.Sprint(inc.OptArgs) != c.args {
+			t.Fatalf("Expected %s for %v, got %v, %v", c.args, c.argv, inc.OptArgs, err)
+		}
+	}
+	if err := p.ParseArgv([]string{ "+I" }); err != nil {
+		t.Fatal(err)
+	}
+	inc.OptArgs[0] = "changed"
+	inc.OptArgs = append(inc.OptArgs, "more")
+	if fmt.Sprint(inc.Default) != "[/usr/include /opt/include]" {
+		t.Fatalf("Expected the default unchanged, got %v", inc.Default)
+	}
+	empty := p.NewOptVec('L', "lib", "library directory")
+	if err := p.ParseArgv([]string{ "-L", "x", "+L" }); err != nil || empty.OptArgs == nil || len(empty.OptArgs) != 0 {
+		t.Fatalf("Expected no arguments without a default, got %v, %v", empty.OptArgs, err)
+	}
+}
+
+//Test that ParseArgvRest returns each parse's positional arguments
+//and leaves Rest alone
+func TestParseArgvRest(t *testing.T) {
+	Reset()
+	defer Reset()
+	v := NewFlag('v', "verbose", "print more")
+	Rest = []string{ "untouched" }
+	rest, err := ParseArgvRest([]string{ "a", "-v", "b" })
+	if err != nil || fmt.Sprint(rest) != "[a b]" || !v.Passed {
+		t.Fatalf("Expected [a b] and -v, got %v %t, %v", rest, v.Passed, err)
+	}
+	second, err := ParseArgvRest([]string{ "c", "--", "-v" })
+	if err != nil || fmt.Sprint(second) != "[c -v]" || fmt.Sprint(rest) != "[a b]" {
+		t.Fatalf("Expected [c -v] and the first result unchanged, got %v %v, %v", second, rest, err)
+	}
+	if fmt.Sprint(Rest) != "[untouched]" {
+		t.Fatalf("Expected Rest untouched, got %v", Rest)
+	}
+	if _, err := ParseArgvRest([]string{ "--bogus" }); err == nil {
+		t.Fatal("Expected an error for --bogus")
+	}
+}
+
+//Test that long help strings are wrapped at spaces to HelpWidth, or
+//COLUMNS, with continuation lines indented to the help
+func TestHelpWrap(t *testing.T) {
+	defer func(w int, columns string) { HelpWidth = w; os.Setenv("COLUMNS", columns) }(HelpWidth, os.Getenv("COLUMNS"))
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion = "prog", "1.0"
+	p.NewOptArg('f', "file", "file to read the input from, or standard input if none is given")
+	p.NewFlag('v', "verbose", "print more")
+	HelpWidth = 40
+	expected := "prog - 1.0\n" +
+		"-f/--file     file to read the input\n" +
+		"              from, or standard input if\n" +
+		"              none is given\n" +
+		"-v/--verbose  print more\n"
+	if help := p.HelpString(); help != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, help)
+	}
+	HelpWidth = 0
+	os.Setenv("COLUMNS", "50")
+	expected = "prog - 1.0\n" +
+		"-f/--file     file to read the input from, or\n" +
+		"              standard input if none is given\n" +
+		"-v/--verbose  print more\n"
+	if help := p.HelpString(); help != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, help)
+	}
+	os.Unsetenv("COLUMNS")
+	if help := p.HelpString(); strings.Count(help, "\n") != 3 {
+		t.Fatalf("Expected no wrapping at 80 columns, got:\n%s", help)
+	}
+}
+
+//Test that PositionalHandler is given each positional argument as it
+//comes, in place of Rest
+func TestPositionalHandler(t *testing.T) {
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	seen := make([]string, 0, initialCapacity)
+	PositionalHandler = func(arg string) error {
+		seen = append(seen, fmt.Sprintf("%s:%t", arg, v.Passed))
+		return nil
+	}
+	defer func() { PositionalHandler = nil }()
+	if err := p.ParseArgv([]string{ "a", "-v", "b", "-5", "+v", "c", "--", "-v" }); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(seen) != "[a:false b:true -5:true c:false -v:false]" || len(p.Rest) != 0 {
+		t.Fatalf("Expected the positionals in order and Rest empty, got %v %v", seen, p.Rest)
+	}
+	stop := errors.New("stop")
+	PositionalHandler = func(arg string) error {
+		if arg == "bad" {
+			return stop
+		}
+		return nil
+	}
+	if err := p.ParseArgv([]string{ "ok", "bad", "-v" }); err != stop {
+		t.Fatalf("Expected the handler's error, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package getopt
+
+import(
+	"sort"
+	"strings"
+)
+
+//Whether an option takes an argument, as in the has_arg field
+//of C's struct option
+const (
+	NoArgument = iota
+	RequiredArgument
+	OptionalArgument
+)
+
+//Description of a long option, mirroring C's struct option
+type LongOption struct {
+	//Long name, without the leading "--"
+	Name	string
+	//One of NoArgument, RequiredArgument or OptionalArgument
+	HasArg	int
+	//The short name of the option, or 0 if there is none
+	Val	byte
+}
+
+//Whether an option takes no, a required or an optional argument
+func hasArg(opt Option) int {
+	if o, ok := opt.(*OptArg); ok && o.ArgOptional {
+		return OptionalArgument
+	}
+	if takesArg(opt) {
+		return RequiredArgument
+	}
+	return NoArgument
+}
+
+//Return the registered short options as a getopt(3) option string,
+//e.g., "ab:c" where b takes an argument and a and c do not.
+//Options are given in byte order
+func GetoptString() string {
+	return defaultParser.GetoptString()
+}
+
+//Return the registered short options as a getopt(3) option string,
+//e.g., "ab:c" where b takes an argument and a and c do not.
+//Options are given in byte order
+func (p *Parser) GetoptString() string {
+	shorts := make([]int, 0, len(p.optByShort))
+	for short := range p.optByShort {
+		if short != 0 {
+			shorts = append(shorts, int(short))
+		}
+	}
+	sort.Ints(shorts)
+	var b strings.Builder
+	for _, short := range shorts {
+		b.WriteByte(byte(short))
+		switch hasArg(p.optByShort[byte(short)]) {
+		case RequiredArgument:
+			b.WriteString(":")
+		case OptionalArgument:
+			b.WriteString("::")
+		}
+	}
+	return b.String()
+}
+
+//Return the registered long options as getopt_long(3) descriptors,
+//sorted by name
+func GetoptLongOptions() []LongOption {
+	return defaultParser.GetoptLongOptions()
+}
+
+//Return the registered long options as getopt_long(3) descriptors,
+//sorted by name
+func (p *Parser) GetoptLongOptions() []LongOption {
+	longs := make([]string, 0, len(p.optByLong))
+	for long := range p.optByLong {
+		longs = append(longs, long)
+	}
+	sort.Strings(longs)
+	opts := make([]LongOption, 0, len(longs))
+	for _, long := range longs {
+		opt := p.optByLong[long]
+		opts = append(opts, LongOption{
+			Name:	long,
+			HasArg:	hasArg(opt),
+			Val:	optShort(opt),
+		})
+	}
+	return opts
+}
@@ -0,0 +1,36 @@
+package getopt
+
+import(
+	"testing"
+)
+
+//Check the option string and descriptors for a known option set
+func TestGetoptString(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('a', "all", "everything")
+	f := p.NewOptArg('f', "file", "file to read")
+	p.NewOptCount('v', "verbose", "verbosity")
+	p.NewOptVec('I', "include", "include directory")
+	if s := p.GetoptString(); s != "I:af:v" {
+		t.Fatalf("Expected 'I:af:v', got '%s'", s)
+	}
+	expected := []LongOption{
+		{ "all", NoArgument, 'a' },
+		{ "file", RequiredArgument, 'f' },
+		{ "include", RequiredArgument, 'I' },
+		{ "verbose", NoArgument, 'v' },
+	}
+	opts := p.GetoptLongOptions()
+	if len(opts) != len(expected) {
+		t.Fatalf("Expected %d long options, got %d", len(expected), len(opts))
+	}
+	for i := range expected {
+		if opts[i] != expected[i] {
+			t.Fatalf("Expected %v, got %v", expected[i], opts[i])
+		}
+	}
+	f.ArgOptional = true
+	if s := p.GetoptString(); s != "I:af::v" {
+		t.Fatalf("Expected 'I:af::v', got '%s'", s)
+	}
+}
@@ -0,0 +1,87 @@
+package getopt
+
+import(
+	"fmt"
+	"strings"
+)
+
+//A set of options of which at most one may be passed.  If the
+//group is required, exactly one of them must be passed.  Groups
+//are checked after the whole argument vector has been parsed
+type ExclusiveGroup struct {
+	//Name of the group, used in error messages
+	Name		string
+	//Whether one of the options must be passed
+	Required	bool
+	//The options in the group
+	Opts		[]Option
+}
+
+//A group that was violated during the last parse, and the
+//options from that group which were passed.  If Options
+//is empty, the group was required but none were passed
+type GroupViolation struct {
+	Group	*ExclusiveGroup
+	Options	[]string
+}
+
+//Create a new group of mutually exclusive options.  The options
+//must be pointers returned by the option constructors
+func NewExclusiveGroup(name string, required bool, opts ...Option) *ExclusiveGroup {
+	return defaultParser.NewExclusiveGroup(name, required, opts...)
+}
+
+//Create a new group of mutually exclusive options.  The options
+//must be pointers returned by the option constructors
+func (p *Parser) NewExclusiveGroup(name string, required bool, opts ...Option) *ExclusiveGroup {
+	g := &ExclusiveGroup{
+		Name:		name,
+		Required:	required,
+		Opts:		opts,
+	}
+	p.exclusiveGroups = append(p.exclusiveGroups, g)
+	return g
+}
+
+//Return every exclusive group violated during the last parse,
+//along with the offending options
+func GroupViolations() []GroupViolation {
+	return defaultParser.GroupViolations()
+}
+
+//Return every exclusive group violated during the last parse,
+//along with the offending options
+func (p *Parser) GroupViolations() []GroupViolation {
+	return p.groupViolations
+}
+
+//Check every exclusive group against the options seen, recording
+//the violations.  Returns an error summarizing them, or nil
+func (p *Parser) checkGroups() error {
+	for _, g := range p.exclusiveGroups {
+		passed := make([]string, 0, len(g.Opts))
+		for _, opt := range g.Opts {
+			if p.seenOpts[opt] {
+				passed = append(passed, longPrefix() + optLong(opt))
+			}
+		}
+		if len(passed) > 1 || (g.Required && len(passed) == 0) {
+			p.groupViolations = append(p.groupViolations, GroupViolation{
+				Group:		g,
+				Options:	passed,
+			})
+		}
+	}
+	if len(p.groupViolations) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(p.groupViolations))
+	for _, v := range p.groupViolations {
+		if len(v.Options) == 0 {
+			msgs = append(msgs, fmt.Sprintf("one of group %s is required", v.Group.Name))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("%s are mutually exclusive", strings.Join(v.Options, ", ")))
+		}
+	}
+	return usageErrorf("Exclusive group violation:  %s", strings.Join(msgs, "; "))
+}
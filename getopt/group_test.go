@@ -0,0 +1,91 @@
+package getopt
+
+import(
+	"testing"
+)
+
+//Check that every violated group is reported, not just the first
+func TestGroupViolations(t *testing.T) {
+	p := NewParser()
+	j := p.NewFlag('j', "json", "json output")
+	y := p.NewFlag('y', "yaml", "yaml output")
+	q := p.NewFlag('q', "quiet", "no output")
+	l := p.NewFlag('l', "loud", "more output")
+	p.NewExclusiveGroup("format", false, j, y)
+	p.NewExclusiveGroup("volume", false, q, l)
+	argv := []string { "--json", "-y", "-ql" }
+	if err := p.ParseArgv(argv); err == nil {
+		t.Fatal("Expected error for violated groups")
+	}
+	v := p.GroupViolations()
+	if len(v) != 2 {
+		t.Fatalf("Expected 2 violations, got %d", len(v))
+	}
+	if v[0].Group.Name != "format" || len(v[0].Options) != 2 {
+		t.Fatalf("Expected format group with 2 options, got %v", v[0])
+	}
+	if v[0].Options[0] != "--json" || v[0].Options[1] != "--yaml" {
+		t.Fatalf("Expected --json and --yaml, got %v", v[0].Options)
+	}
+	if v[1].Group.Name != "volume" || len(v[1].Options) != 2 {
+		t.Fatalf("Expected volume group with 2 options, got %v", v[1])
+	}
+}
+
+//Check that a required group with nothing passed is a violation
+func TestGroupRequired(t *testing.T) {
+	p := NewParser()
+	j := p.NewFlag('j', "json", "json output")
+	y := p.NewFlag('y', "yaml", "yaml output")
+	p.NewExclusiveGroup("format", true, j, y)
+	if err := p.ParseArgv([]string{}); err == nil {
+		t.Fatal("Expected error for missing required group")
+	}
+	v := p.GroupViolations()
+	if len(v) != 1 || len(v[0].Options) != 0 {
+		t.Fatalf("Expected one violation with no options, got %v", v)
+	}
+	if err := p.ParseArgv([]string{ "-j" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if len(p.GroupViolations()) != 0 {
+		t.Fatal("Expected violations to be cleared")
+	}
+}
+
+//Check that a required group renders as a single synopsis token
+func TestGroupUsage(t *testing.T) {
+	p := NewParser()
+	p.ProgramName = "prog"
+	j := p.NewFlag('j', "json", "json output")
+	y := p.NewFlag('y', "yaml", "yaml output")
+	p.NewOptArg('o', "output", "output file")
+	p.NewFlag('v', "verbose", "print more")
+	p.NewExclusiveGroup("format", true, j, y)
+	expected := "usage: prog [-o OUTPUT] [-v] (-j | -y) [args...]"
+	if u := p.Usage(); u != expected {
+		t.Fatalf("Expected '%s', got '%s'", expected, u)
+	}
+}
+
+//Check that a parse failing before the groups are checked forgets
+//the violations of the parse before it
+func TestGroupViolationsCleared(t *testing.T) {
+	p := NewParser()
+	j := p.NewFlag('j', "json", "json output")
+	y := p.NewFlag('y', "yaml", "yaml output")
+	p.NewExclusiveGroup("format", false, j, y)
+	p.NewOptArg('o', "output", "output file").Required = true
+	if err := p.ParseArgv([]string{ "-jy", "-o", "out" }); err == nil || len(p.GroupViolations()) != 1 {
+		t.Fatalf("Expected one violation, got %v, %v", p.GroupViolations(), err)
+	}
+	if err := p.ParseArgv([]string{ "-j" }); err == nil || len(p.GroupViolations()) != 0 {
+		t.Fatalf("Expected a missing --output and no violations, got %v, %v", p.GroupViolations(), err)
+	}
+	if err := p.ParseArgv([]string{ "-jy", "-o", "out" }); err == nil {
+		t.Fatal("Expected error for violated group")
+	}
+	if err := p.ParseArgv([]string{ "--bogus" }); err == nil || len(p.GroupViolations()) != 0 {
+		t.Fatalf("Expected an unknown option and no violations, got %v, %v", p.GroupViolations(), err)
+	}
+}
@@ -0,0 +1,113 @@
+package getopt
+
+import(
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+//Encode the values of the options as a JSON object keyed by long
+//name, as given by Values
+func ValuesJSON() ([]byte, error) {
+	return defaultParser.ValuesJSON()
+}
+
+//Encode the values of the options as a JSON object keyed by long
+//name, as given by Values
+func (p *Parser) ValuesJSON() ([]byte, error) {
+	return json.Marshal(p.Values())
+}
+
+//Set options from a JSON object keyed by long name, as written by
+//ValuesJSON.  Each value must have the type of its option.  The
+//whole object is checked before any option is set.  Arguments of
+//OptArgs and OptVecs are checked against their choices and
+//validators, but OnSet is not called
+func SetValuesJSON(data []byte) error {
+	return defaultParser.SetValuesJSON(data)
+}
+
+//Set options from a JSON object keyed by long name, as written by
+//ValuesJSON.  Each value must have the type of its option.  The
+//whole object is checked before any option is set.  Arguments of
+//OptArgs and OptVecs are checked against their choices and
+//validators, but OnSet is not called
+func (p *Parser) SetValuesJSON(data []byte) error {
+	raw := make(map[string]json.RawMessage, initialCapacity)
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	values := make(map[Option]any, len(raw))
+	for long, msg := range raw {
+		opt, ok := p.optByLong[long]
+		if !ok {
+			return &UsageError{ &UnknownOptionError{ long, true } }
+		}
+		value := optValue(opt)
+		var err error
+		switch value.(type) {
+		case bool:
+			var b bool
+			err, value = json.Unmarshal(msg, &b), b
+		case string:
+			var s string
+			err, value = json.Unmarshal(msg, &s), s
+		case []string:
+			var v []string
+			err, value = json.Unmarshal(msg, &v), v
+		case int64:
+			var n int64
+			err, value = json.Unmarshal(msg, &n), n
+		case float64:
+			var x float64
+			err, value = json.Unmarshal(msg, &x), x
+		case time.Duration:
+			var d time.Duration
+			err, value = json.Unmarshal(msg, &d), d
+		}
+		if err != nil {
+			return parseValueErrorf(long, string(msg), "Invalid value %s for %s%s, expected %T", msg, longPrefix(), long, optValue(opt))
+		}
+		switch opt.(type) {
+		case *OptArg:
+			o := opt.(*OptArg)
+			if err := o.checkChoice(value.(string)); err != nil {
+				return err
+			}
+			if err := validate(o.Validate, o.Long, value.(string)); err != nil {
+				return err
+			}
+		case *OptVec:
+			v := opt.(*OptVec)
+			for _, arg := range value.([]string) {
+				if err := validate(v.Validate, v.Long, arg); err != nil {
+					return err
+				}
+			}
+		}
+		values[opt] = value
+	}
+	for opt, value := range values {
+		switch opt.(type) {
+		case *Flag:
+			opt.(*Flag).Passed = value.(bool)
+		case *OptArg:
+			opt.(*OptArg).Opt = value.(string)
+		case *OptVec:
+			v := opt.(*OptVec)
+			v.OptArgs = append(make([]string, 0, len(value.([]string))), value.([]string)...)
+			v.defaulted = false
+		case *OptCount:
+			opt.(*OptCount).Count = value.(int64)
+		case *OptInt:
+			opt.(*OptInt).Value = value.(int64)
+		case *OptFloat:
+			opt.(*OptFloat).Value = value.(float64)
+		case *OptDuration:
+			opt.(*OptDuration).Value = value.(time.Duration)
+		default:
+			panic(fmt.Sprintf("Invalid flag type %T", opt))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package getopt
+
+import(
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+//Register one option of each type
+func jsonParser() *Parser {
+	p := NewParser()
+	p.NewFlag('q', "quiet", "print less")
+	p.NewOptArg('c', "color", "when to color").SetChoices("always", "never", "auto")
+	p.NewOptVec('I', "include", "include directory")
+	p.NewOptCount('v', "verbose", "verbosity")
+	p.NewOptInt('p', "port", "port to listen on")
+	p.NewOptFloat('r', "rate", "sampling rate")
+	return p
+}
+
+//Check the shape of the JSON written
+func TestValuesJSON(t *testing.T) {
+	p := jsonParser()
+	if err := p.ParseArgv([]string{ "-q", "-c", "never", "-Ia", "-Ib", "-vvv", "-p", "8080", "-r", "0.25" }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.ValuesJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"color":"never","include":["a","b"],"port":8080,"quiet":true,"rate":0.25,"verbose":3}`
+	if string(data) != expected {
+		t.Fatalf("Expected %s, got %s", expected, data)
+	}
+	data, _ = jsonParser().ValuesJSON()
+	expected = `{"color":"","include":[],"port":0,"quiet":false,"rate":0,"verbose":0}`
+	if string(data) != expected {
+		t.Fatalf("Expected %s before parsing, got %s", expected, data)
+	}
+}
+
+//Check that unmarshaling what was marshaled gives the same values
+func TestJSONRoundTrip(t *testing.T) {
+	p := jsonParser()
+	if err := p.ParseArgv([]string{ "-c", "auto", "-I", "x y", "-vv", "-p", "-3", "-r", "1e-3" }); err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.ValuesJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := jsonParser()
+	if err := q.SetValuesJSON(data); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(q.Values()) != fmt.Sprint(p.Values()) {
+		t.Fatalf("Expected %v, got %v", p.Values(), q.Values())
+	}
+}
+
+//Check that bad JSON values are rejected without setting anything
+func TestSetValuesJSONErrors(t *testing.T) {
+	cases := []struct {
+		data	string
+		long	string
+	}{
+		{ `{"quiet":true,"port":"80"}`, "port" },
+		{ `{"quiet":true,"color":"blue"}`, "color" },
+		{ `{"quiet":true,"include":"a"}`, "include" },
+		{ `{"quiet":true,"verbose":1.5}`, "verbose" },
+	}
+	for _, c := range cases {
+		p := jsonParser()
+		err := p.SetValuesJSON([]byte(c.data))
+		var valueErr *ParseValueError
+		if !errors.As(err, &valueErr) || valueErr.Long != c.long {
+			t.Fatalf("%s: expected an error for --%s, got %v", c.data, c.long, err)
+		}
+		if q, _ := p.GetFlag("quiet"); q {
+			t.Fatalf("%s: expected nothing to be set", c.data)
+		}
+	}
+	var unknown *UnknownOptionError
+	if err := jsonParser().SetValuesJSON([]byte(`{"bogus":1}`)); !errors.As(err, &unknown) {
+		t.Fatalf("Expected an unknown option error, got %v", err)
+	}
+}
+
+//Check that a Command is encoded as a struct, not as the values of
+//its options
+func TestCommandJSON(t *testing.T) {
+	p := NewParser()
+	cmd := p.AddCommand("commit", "record changes", func([]string) error { return nil })
+	cmd.NewFlag('a', "all", "commit all changes")
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil || fields["Name"] != "commit" || fields["Desc"] != "record changes" {
+		t.Fatalf("Expected the command's Name and Desc, got %s, %v", data, err)
+	}
+	if _, ok := fields["all"]; ok {
+		t.Fatalf("Expected no option values, got %s", data)
+	}
+}
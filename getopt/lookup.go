@@ -0,0 +1,144 @@
+package getopt
+
+import(
+	"time"
+)
+
+//Functions to look up option values by long name, or alias, for
+//code that does not hold the options themselves.  Each returns
+//false if there is no such option or it is of another type
+
+//Return whether the flag named long is set
+func GetFlag(long string) (bool, bool) {
+	return defaultParser.GetFlag(long)
+}
+
+//Return whether the flag named long is set
+func (p *Parser) GetFlag(long string) (bool, bool) {
+	if o, ok := p.optByLong[long].(*Flag); ok {
+		return o.Get(), true
+	}
+	return false, false
+}
+
+//Return the argument of the OptArg named long
+func GetString(long string) (string, bool) {
+	return defaultParser.GetString(long)
+}
+
+//Return the argument of the OptArg named long
+func (p *Parser) GetString(long string) (string, bool) {
+	if o, ok := p.optByLong[long].(*OptArg); ok {
+		return o.Get(), true
+	}
+	return "", false
+}
+
+//Return the arguments of the OptVec named long
+func GetStrings(long string) ([]string, bool) {
+	return defaultParser.GetStrings(long)
+}
+
+//Return the arguments of the OptVec named long
+func (p *Parser) GetStrings(long string) ([]string, bool) {
+	if o, ok := p.optByLong[long].(*OptVec); ok {
+		return o.Get(), true
+	}
+	return nil, false
+}
+
+//Return the count of the OptCount named long
+func GetCount(long string) (int64, bool) {
+	return defaultParser.GetCount(long)
+}
+
+//Return the count of the OptCount named long
+func (p *Parser) GetCount(long string) (int64, bool) {
+	if o, ok := p.optByLong[long].(*OptCount); ok {
+		return o.Get(), true
+	}
+	return 0, false
+}
+
+//Return the value of the OptInt named long
+func GetInt(long string) (int64, bool) {
+	return defaultParser.GetInt(long)
+}
+
+//Return the value of the OptInt named long
+func (p *Parser) GetInt(long string) (int64, bool) {
+	if o, ok := p.optByLong[long].(*OptInt); ok {
+		return o.Get(), true
+	}
+	return 0, false
+}
+
+//Return the value of the OptFloat named long
+func GetFloat(long string) (float64, bool) {
+	return defaultParser.GetFloat(long)
+}
+
+//Return the value of the OptFloat named long
+func (p *Parser) GetFloat(long string) (float64, bool) {
+	if o, ok := p.optByLong[long].(*OptFloat); ok {
+		return o.Get(), true
+	}
+	return 0, false
+}
+
+//Return the value of the OptDuration named long
+func GetDuration(long string) (time.Duration, bool) {
+	return defaultParser.GetDuration(long)
+}
+
+//Return the value of the OptDuration named long
+func (p *Parser) GetDuration(long string) (time.Duration, bool) {
+	if o, ok := p.optByLong[long].(*OptDuration); ok {
+		return o.Get(), true
+	}
+	return 0, false
+}
+
+//Return the value of an option without marking it read:  a bool
+//for a Flag, a string for an OptArg, a copy of the []string for
+//an OptVec, an int64 for an OptCount or OptInt, a float64 for
+//an OptFloat and a time.Duration for an OptDuration
+func optValue(opt Option) any {
+	switch opt.(type) {
+	case *Flag:
+		return opt.(*Flag).Passed
+	case *OptArg:
+		return opt.(*OptArg).Opt
+	case *OptVec:
+		args := opt.(*OptVec).OptArgs
+		return append(make([]string, 0, len(args)), args...)
+	case *OptCount:
+		return opt.(*OptCount).Count
+	case *OptInt:
+		return opt.(*OptInt).Value
+	case *OptFloat:
+		return opt.(*OptFloat).Value
+	case *OptDuration:
+		return opt.(*OptDuration).Value
+	default:
+		panic("Invalid flag type")
+	}
+}
+
+//Return the value of every option, keyed by long name, for logging
+//the configuration.  Options not passed have their default or zero
+//values.  Aliases are left out, and the options are not marked read
+func Values() map[string]any {
+	return defaultParser.Values()
+}
+
+//Return the value of every option, keyed by long name, for logging
+//the configuration.  Options not passed have their default or zero
+//values.  Aliases are left out, and the options are not marked read
+func (p *Parser) Values() map[string]any {
+	values := make(map[string]any, len(p.opts))
+	for _, opt := range p.opts {
+		values[opt.GetLong()] = optValue(opt)
+	}
+	return values
+}
@@ -0,0 +1,88 @@
+package getopt
+
+import(
+	"fmt"
+	"testing"
+)
+
+//Check looking up options by name, and of the wrong type
+func TestLookup(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('q', "quiet", "print less").AddAlias("silent")
+	p.NewOptArg('f', "file", "file to read")
+	p.NewOptVec('I', "include", "include directory")
+	p.NewOptCount('v', "verbose", "verbosity")
+	p.NewOptInt('p', "port", "port to listen on")
+	p.NewOptFloat('r', "rate", "sampling rate")
+	if err := p.ParseArgv([]string{ "-q", "-f", "a", "-Ib", "-vv", "-p80", "-r0.5" }); err != nil {
+		t.Fatal(err)
+	}
+	if q, ok := p.GetFlag("silent"); !q || !ok {
+		t.Fatalf("Expected quiet through its alias, got %t %t", q, ok)
+	}
+	if f, ok := p.GetString("file"); f != "a" || !ok {
+		t.Fatalf("Expected a, got '%s' %t", f, ok)
+	}
+	if inc, ok := p.GetStrings("include"); len(inc) != 1 || inc[0] != "b" || !ok {
+		t.Fatalf("Expected [b], got %v %t", inc, ok)
+	}
+	if v, ok := p.GetCount("verbose"); v != 2 || !ok {
+		t.Fatalf("Expected 2, got %d %t", v, ok)
+	}
+	if port, ok := p.GetInt("port"); port != 80 || !ok {
+		t.Fatalf("Expected 80, got %d %t", port, ok)
+	}
+	if rate, ok := p.GetFloat("rate"); rate != 0.5 || !ok {
+		t.Fatalf("Expected 0.5, got %g %t", rate, ok)
+	}
+	if _, ok := p.GetString("verbose"); ok {
+		t.Fatal("Expected a type mismatch for --verbose")
+	}
+	if _, ok := p.GetFlag("file"); ok {
+		t.Fatal("Expected a type mismatch for --file")
+	}
+	if _, ok := p.GetCount("missing"); ok {
+		t.Fatal("Expected no option --missing")
+	}
+}
+
+//Check that Values gives every option with its typed value
+func TestValues(t *testing.T) {
+	p := NewParser()
+	TrackReads = true
+	defer func() { TrackReads = false }()
+	p.NewFlag('q', "quiet", "print less").AddAlias("silent")
+	p.NewOptArgDefault('f', "file", "file to read", "in.txt")
+	p.NewOptVec('I', "include", "include directory")
+	p.NewOptCount('v', "verbose", "verbosity")
+	p.NewOptInt('p', "port", "port to listen on")
+	p.NewOptFloat('r', "rate", "sampling rate")
+	if err := p.ParseArgv([]string{ "--silent", "-Ib", "-Ic", "-vv", "-p80" }); err != nil {
+		t.Fatal(err)
+	}
+	values := p.Values()
+	expected := map[string]any{
+		"quiet": true,
+		"file": "in.txt",
+		"include": []string{ "b", "c" },
+		"verbose": int64(2),
+		"port": int64(80),
+		"rate": float64(0),
+	}
+	if len(values) != len(expected) {
+		t.Fatalf("Expected %d values, got %v", len(expected), values)
+	}
+	for long, want := range expected {
+		got, ok := values[long]
+		if !ok || fmt.Sprintf("%T %v", got, got) != fmt.Sprintf("%T %v", want, want) {
+			t.Fatalf("Expected %T %v for --%s, got %T %v", want, want, long, got, got)
+		}
+	}
+	values["include"].([]string)[0] = "x"
+	if s, _ := p.GetStrings("include"); s[0] != "b" {
+		t.Fatal("Expected Values to copy the arguments of an OptVec")
+	}
+	if unread := p.UnreadOptions(); len(unread) != 3 {
+		t.Fatalf("Expected Values not to mark options read, got %v unread", unread)
+	}
+}
@@ -0,0 +1,205 @@
+package getopt
+
+//An option created by one of the constructors:  a *Flag, *OptArg,
+//*OptVec, *OptCount, *OptInt, *OptFloat or *OptDuration.  The same pointer is
+//stored everywhere the Parser keeps track of the option, so it
+//always reflects the last parse
+type Option interface {
+	//Long name of the option, without the leading "--"
+	GetLong() string
+	//Short name of the option, or 0 if it has none
+	GetShort() byte
+	//Help string of the option
+	GetHelp() string
+	//Heading the option is listed under in the help
+	GetCategory() string
+	//Whether the option is left out of the help and usage
+	IsHidden() bool
+}
+
+//Return every option, in the order they were created
+func Options() []Option {
+	return defaultParser.Options()
+}
+
+//Return every option, in the order they were created
+func (p *Parser) Options() []Option {
+	opts := make([]Option, len(p.opts))
+	copy(opts, p.opts)
+	return opts
+}
+
+//Return the long name of the option
+func (f *Flag) GetLong() string {
+	return f.Long
+}
+
+//Return the long name of the option
+func (o *OptArg) GetLong() string {
+	return o.Long
+}
+
+//Return the long name of the option
+func (v *OptVec) GetLong() string {
+	return v.Long
+}
+
+//Return the long name of the option
+func (c *OptCount) GetLong() string {
+	return c.Long
+}
+
+//Return the long name of the option
+func (n *OptInt) GetLong() string {
+	return n.Long
+}
+
+//Return the long name of the option
+func (x *OptFloat) GetLong() string {
+	return x.Long
+}
+
+//Return the long name of the option
+func (d *OptDuration) GetLong() string {
+	return d.Long
+}
+
+//Return the short name of the option
+func (f *Flag) GetShort() byte {
+	return f.Short
+}
+
+//Return the short name of the option
+func (o *OptArg) GetShort() byte {
+	return o.Short
+}
+
+//Return the short name of the option
+func (v *OptVec) GetShort() byte {
+	return v.Short
+}
+
+//Return the short name of the option
+func (c *OptCount) GetShort() byte {
+	return c.Short
+}
+
+//Return the short name of the option
+func (n *OptInt) GetShort() byte {
+	return n.Short
+}
+
+//Return the short name of the option
+func (x *OptFloat) GetShort() byte {
+	return x.Short
+}
+
+//Return the short name of the option
+func (d *OptDuration) GetShort() byte {
+	return d.Short
+}
+
+//Return the help string of the option
+func (f *Flag) GetHelp() string {
+	return f.Help
+}
+
+//Return the help string of the option
+func (o *OptArg) GetHelp() string {
+	return o.Help
+}
+
+//Return the help string of the option
+func (v *OptVec) GetHelp() string {
+	return v.Help
+}
+
+//Return the help string of the option
+func (c *OptCount) GetHelp() string {
+	return c.Help
+}
+
+//Return the help string of the option
+func (n *OptInt) GetHelp() string {
+	return n.Help
+}
+
+//Return the help string of the option
+func (x *OptFloat) GetHelp() string {
+	return x.Help
+}
+
+//Return the help string of the option
+func (d *OptDuration) GetHelp() string {
+	return d.Help
+}
+
+//Return the heading the option is listed under in the help
+func (f *Flag) GetCategory() string {
+	return f.Category
+}
+
+//Return the heading the option is listed under in the help
+func (o *OptArg) GetCategory() string {
+	return o.Category
+}
+
+//Return the heading the option is listed under in the help
+func (v *OptVec) GetCategory() string {
+	return v.Category
+}
+
+//Return the heading the option is listed under in the help
+func (c *OptCount) GetCategory() string {
+	return c.Category
+}
+
+//Return the heading the option is listed under in the help
+func (n *OptInt) GetCategory() string {
+	return n.Category
+}
+
+//Return the heading the option is listed under in the help
+func (x *OptFloat) GetCategory() string {
+	return x.Category
+}
+
+//Return the heading the option is listed under in the help
+func (d *OptDuration) GetCategory() string {
+	return d.Category
+}
+
+//Return whether the option is left out of the help and usage
+func (f *Flag) IsHidden() bool {
+	return f.Hidden
+}
+
+//Return whether the option is left out of the help and usage
+func (o *OptArg) IsHidden() bool {
+	return o.Hidden
+}
+
+//Return whether the option is left out of the help and usage
+func (v *OptVec) IsHidden() bool {
+	return v.Hidden
+}
+
+//Return whether the option is left out of the help and usage
+func (c *OptCount) IsHidden() bool {
+	return c.Hidden
+}
+
+//Return whether the option is left out of the help and usage
+func (n *OptInt) IsHidden() bool {
+	return n.Hidden
+}
+
+//Return whether the option is left out of the help and usage
+func (x *OptFloat) IsHidden() bool {
+	return x.Hidden
+}
+
+//Return whether the option is left out of the help and usage
+func (d *OptDuration) IsHidden() bool {
+	return d.Hidden
+}
@@ -0,0 +1,38 @@
+package getopt
+
+import(
+	"testing"
+)
+
+//Check that options read back from the registry are the ones
+//returned by the constructors, holding the parsed values
+func TestRegistryPointers(t *testing.T) {
+	p := NewParser()
+	p.NewFlag('v', "verbose", "print more")
+	p.NewOptArg('f', "file", "file to read").AddAlias("input")
+	p.NewOptCount('q', "quiet", "print less")
+	if err := p.ParseArgv([]string{ "-v", "--input", "a.txt", "-qq" }); err != nil {
+		t.Fatal(err)
+	}
+	if f := p.optByLong["file"].(*OptArg); f.Opt != "a.txt" || f != p.optByLong["input"] || f != p.optByShort['f'] || f != p.opts[1] {
+		t.Fatalf("Expected the same *OptArg everywhere with a.txt, got '%s'", f.Opt)
+	}
+	for _, opt := range p.Options() {
+		if p.optByLong[opt.GetLong()] != opt || p.optByShort[opt.GetShort()] != opt {
+			t.Fatalf("Expected --%s to be stored by pointer", opt.GetLong())
+		}
+		switch o := opt.(type) {
+		case *Flag:
+			if !o.Passed || o != p.opts[0] {
+				t.Fatal("Expected -v to be set in the registry")
+			}
+		case *OptCount:
+			if o.Count != 2 || o != p.opts[2] {
+				t.Fatalf("Expected -qq to count 2 in the registry, got %d", o.Count)
+			}
+		}
+	}
+	if h := p.Options()[1].GetHelp(); h != "file to read" {
+		t.Fatalf("Expected the help of --file, got %s", h)
+	}
+}
@@ -0,0 +1,257 @@
+package getopt
+
+import(
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+//A set of options, and the results of parsing arguments against
+//them.  Each Parser is independent of the others, so, e.g., a
+//program and its plugins can each have their own.  Calls to
+//ParseArgv and Reset on the same Parser may be made from several
+//goroutines, and are run one at a time, but the options should
+//only be read once parsing is done.  The package
+//level functions use a default Parser, taking its program name,
+//version and description from the package level variables, and
+//storing its Rest and OptInd there after parsing
+type Parser struct {
+	//All arguments that were not program options
+	Rest	[]string
+	//Index in argv just past the last option, option argument or
+	//"--" taken by the last parse, or 0 if there were none
+	OptInd	int
+	//Current program version, used for printing version information
+	ProgramVersion	string
+	//Program name, if different from argv[0]
+	ProgramName	string
+	//Description of the program
+	ProgramDesc	string
+
+	//Map of bytes to their associated options.  Used for parsing
+	//short options
+	optByShort	map[byte]Option
+	//Map of strings to options, used to parse long options
+	optByLong	map[string]Option
+	//Every option, in the order created
+	opts		[]Option
+	//List of exclusive groups created
+	exclusiveGroups	[]*ExclusiveGroup
+	//Groups violated during the last parse
+	groupViolations	[]GroupViolation
+	//Options encountered during the last parse
+	seenOpts	map[Option]bool
+	//Options read through their Get methods since the last parse
+	readOpts	map[Option]bool
+	//Additional long names of options, added by AddAlias
+	aliases		map[Option][]string
+	//Non-ASCII short names of options, added by SetShortRune
+	optByRune	map[rune]Option
+	shortRunes	map[Option]rune
+	//Flags registered by EnableHelpVersion
+	helpFlag	*Flag
+	versionFlag	*Flag
+	//Struct fields bound to options by Bind
+	bindings	[]binding
+	//Commands registered by AddCommand, by name
+	commands	map[string]*Command
+	//Whether options end at the first non-option, as when
+	//parsing the options before a command
+	inOrder		bool
+	//Held while parsing or resetting
+	mu		sync.Mutex
+}
+
+//Create a new Parser with no options
+func NewParser() *Parser {
+	p := &Parser{}
+	p.Reset()
+	return p
+}
+
+//The Parser used by the package level functions
+var defaultParser = NewParser()
+
+//Copy the program information from the package level variables
+//to the default parser
+func loadDefault() {
+	defaultParser.ProgramName = ProgramName
+	defaultParser.ProgramVersion = ProgramVersion
+	defaultParser.ProgramDesc = ProgramDesc
+}
+
+//Forget every option and exclusive group created, the results of
+//the last parse, and the program name, version and description.
+//Settings such as OptionPrefix are left alone
+func Reset() {
+	defaultParser.mu.Lock()
+	defer defaultParser.mu.Unlock()
+	defaultParser.reset()
+	Rest = defaultParser.Rest
+	OptInd = 0
+	ProgramName = ""
+	ProgramVersion = ""
+	ProgramDesc = ""
+}
+
+//Forget every option and exclusive group created, the results of
+//the last parse, and the program name, version and description.
+//Settings such as OptionPrefix are left alone
+func (p *Parser) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reset()
+}
+
+//Forget everything, as Reset does, with the parser already locked
+func (p *Parser) reset() {
+	p.optByShort = make(map[byte]Option, initialCapacity)
+	p.optByLong = make(map[string]Option, initialCapacity)
+	p.opts = make([]Option, 0, initialCapacity)
+	p.exclusiveGroups = make([]*ExclusiveGroup, 0, initialCapacity)
+	p.aliases = make(map[Option][]string, initialCapacity)
+	p.optByRune = make(map[rune]Option, initialCapacity)
+	p.shortRunes = make(map[Option]rune, initialCapacity)
+	p.groupViolations = nil
+	p.seenOpts = make(map[Option]bool, initialCapacity)
+	p.readOpts = make(map[Option]bool, initialCapacity)
+	p.bindings = make([]binding, 0, initialCapacity)
+	p.commands = make(map[string]*Command, initialCapacity)
+	p.inOrder = false
+	p.helpFlag = nil
+	p.versionFlag = nil
+	p.Rest = make([]string, 0, initialCapacity)
+	p.OptInd = 0
+	p.ProgramName = ""
+	p.ProgramVersion = ""
+	p.ProgramDesc = ""
+}
+
+
+//Add an option to the maps by its names.  Panics if either name
+//is invalid or taken, since that is a mistake in the program, not
+//in its arguments.  A short name must be a printable byte other
+//than space, "=" and the option and negation prefixes, or 0 for
+//none
+func (p *Parser) register(opt Option) {
+	short, long := optShort(opt), optLong(opt)
+	if short != 0 && (short <= ' ' || short > '~' || short == OptionPrefix || short == NegationPrefix || short == '=') {
+		panic(fmt.Sprintf("Invalid short option %q for --%s", short, long))
+	}
+	if other, ok := p.optByShort[short]; ok && short != 0 {
+		panic(fmt.Sprintf("Duplicate short option -%c:  --%s and --%s", short, optLong(other), long))
+	}
+	p.checkLong(long)
+	if short != 0 {
+		p.optByShort[short] = opt
+	}
+	p.optByLong[long] = opt
+	p.opts = append(p.opts, opt)
+}
+
+//Remove the option with a long name or alias, with all its names,
+//so it is no longer parsed and a replacement can be created under
+//the same names.  It is also dropped from its exclusive groups and
+//bindings.  Returns false if there is no such option
+func Unregister(long string) bool {
+	return defaultParser.Unregister(long)
+}
+
+//Remove the option with a long name or alias, with all its names,
+//so it is no longer parsed and a replacement can be created under
+//the same names.  It is also dropped from its exclusive groups and
+//bindings.  Returns false if there is no such option
+func (p *Parser) Unregister(long string) bool {
+	opt, ok := p.optByLong[long]
+	if !ok {
+		return false
+	}
+	delete(p.optByLong, optLong(opt))
+	for _, alias := range p.aliases[opt] {
+		delete(p.optByLong, alias)
+	}
+	delete(p.aliases, opt)
+	if optShort(opt) != 0 {
+		delete(p.optByShort, optShort(opt))
+	}
+	if r, ok := p.shortRunes[opt]; ok {
+		delete(p.optByRune, r)
+		delete(p.shortRunes, opt)
+	}
+	delete(p.seenOpts, opt)
+	delete(p.readOpts, opt)
+	opts := make([]Option, 0, len(p.opts))
+	for _, o := range p.opts {
+		if o != opt {
+			opts = append(opts, o)
+		}
+	}
+	p.opts = opts
+	if p.helpFlag == opt {
+		p.helpFlag = nil
+	}
+	if p.versionFlag == opt {
+		p.versionFlag = nil
+	}
+	for _, g := range p.exclusiveGroups {
+		members := make([]Option, 0, len(g.Opts))
+		for _, o := range g.Opts {
+			if o != opt {
+				members = append(members, o)
+			}
+		}
+		g.Opts = members
+	}
+	bindings := make([]binding, 0, len(p.bindings))
+	for _, b := range p.bindings {
+		if b.opt != opt {
+			bindings = append(bindings, b)
+		}
+	}
+	p.bindings = bindings
+	return true
+}
+
+//Panic if a long name is empty, contains "=" or whitespace, or is
+//taken, or, if CaseInsensitiveLong is set, one differing from it
+//only in case
+func (p *Parser) checkLong(long string) {
+	if long == "" || strings.Contains(long, "=") || strings.IndexFunc(long, unicode.IsSpace) != -1 {
+		panic(fmt.Sprintf("Invalid long option %q", long))
+	}
+	if _, ok := p.optByLong[long]; ok {
+		panic(fmt.Sprintf("Duplicate long option --%s", long))
+	}
+	for other := range p.optByLong {
+		if foldLong(other) == foldLong(long) {
+			panic(fmt.Sprintf("Long option --%s differs from --%s only in case", long, other))
+		}
+	}
+}
+
+//Return the long names of the options, without their aliases,
+//in sorted order
+func (p *Parser) optionLongs() []string {
+	longs := make([]string, 0, len(p.optByLong))
+	for long, opt := range p.optByLong {
+		if optLong(opt) == long {
+			longs = append(longs, long)
+		}
+	}
+	sort.Strings(longs)
+	return longs
+}
+
+//Return the long names of the options that are not hidden, without
+//their aliases, in sorted order
+func (p *Parser) visibleLongs() []string {
+	longs := make([]string, 0, len(p.optByLong))
+	for _, long := range p.optionLongs() {
+		if !optHidden(p.optByLong[long]) {
+			longs = append(longs, long)
+		}
+	}
+	return longs
+}
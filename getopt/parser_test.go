@@ -0,0 +1,261 @@
+package getopt
+
+import(
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+//Check that parsers with overlapping names parse independently
+func TestParsersIndependent(t *testing.T) {
+	main := NewParser()
+	plugin := NewParser()
+	force := main.NewFlag('f', "force", "force action")
+	file := plugin.NewOptArg('f', "file", "file to read")
+	if err := main.ParseArgv([]string{ "-f", "main.txt" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if err := plugin.ParseArgv([]string{ "-f", "plugin.txt", "extra" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !force.Passed {
+		t.Fatal("Expected -f to set the main parser's flag")
+	}
+	if file.Opt != "plugin.txt" {
+		t.Fatalf("Expected plugin.txt, got %s", file.Opt)
+	}
+	if len(main.Rest) != 1 || main.Rest[0] != "main.txt" {
+		t.Fatalf("Expected [main.txt] in main Rest, got %v", main.Rest)
+	}
+	if len(plugin.Rest) != 1 || plugin.Rest[0] != "extra" {
+		t.Fatalf("Expected [extra] in plugin Rest, got %v", plugin.Rest)
+	}
+	if err := main.ParseArgv([]string{ "--file", "x" }); err == nil {
+		t.Fatal("Expected the plugin's --file to be unknown to the main parser")
+	}
+}
+
+//Check that the package level functions use the default parser
+func TestDefaultParser(t *testing.T) {
+	Reset()
+	v := NewFlag('v', "verbose", "print more")
+	p := NewParser()
+	p.NewOptCount('v', "verbose", "verbosity")
+	if err := ParseArgv([]string{ "-v", "hello" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !v.Passed {
+		t.Fatal("Expected -v to set the default parser's flag")
+	}
+	if len(Rest) != 1 || Rest[0] != "hello" {
+		t.Fatalf("Expected [hello] in Rest, got %v", Rest)
+	}
+	if len(p.Rest) != 0 {
+		t.Fatalf("Expected the other parser's Rest to be untouched, got %v", p.Rest)
+	}
+}
+
+//Check that help and version are triggered by both forms
+func TestHelpVersion(t *testing.T) {
+	defer func(w io.Writer) { HelpWriter = w }(HelpWriter)
+	HelpWriter = io.Discard
+	cases := []struct {
+		argv	[]string
+		err	error
+	}{
+		{ []string{ "-h" }, ErrHelpRequested },
+		{ []string{ "--help" }, ErrHelpRequested },
+		{ []string{ "-V" }, ErrVersionRequested },
+		{ []string{ "--version" }, ErrVersionRequested },
+		{ []string{ "--bogus", "-h" }, ErrHelpRequested },
+		{ []string{ "--file", "--help" }, ErrHelpRequested },
+		{ []string{ "-f", "-h" }, ErrHelpRequested },
+		{ []string{ "--file", "x" }, nil },
+		{ []string{ "--", "--help" }, nil },
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.EnableHelpVersion()
+		p.NewOptArg('f', "file", "file to read")
+		if err := p.ParseArgv(c.argv); err != c.err {
+			t.Fatalf("%v: expected %v, got %v", c.argv, c.err, err)
+		}
+	}
+}
+
+//Check that the version is written to HelpWriter
+func TestPrintVersionWriter(t *testing.T) {
+	defer func(w io.Writer) { HelpWriter = w }(HelpWriter)
+	var b bytes.Buffer
+	HelpWriter = &b
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion = "prog", "1.0"
+	p.PrintVersion()
+	if b.String() != "prog - 1.0\n" {
+		t.Fatalf("Expected \"prog - 1.0\" in HelpWriter, got %q", b.String())
+	}
+}
+
+//Check that registering a taken name panics, naming the option
+func TestDuplicatePanics(t *testing.T) {
+	cases := []struct {
+		name	string
+		reg	func(p *Parser)
+		msg	string
+	}{
+		{ "short", func(p *Parser) { p.NewOptArg('f', "format", "output format") }, "Duplicate short option -f:  --file and --format" },
+		{ "long", func(p *Parser) { p.NewOptCount('x', "file", "another file") }, "Duplicate long option --file" },
+	}
+	for _, c := range cases {
+		p := NewParser()
+		p.NewFlag('f', "file", "file to read")
+		p.NewFlag(0, "quiet", "no short name")
+		p.NewOptVec(0, "include", "no short name either")
+		func() {
+			defer func() {
+				if r := recover(); r != c.msg {
+					t.Fatalf("Expected panic %q for duplicate %s, got %v", c.msg, c.name, r)
+				}
+			}()
+			c.reg(p)
+		}()
+	}
+}
+
+//Check that Options lists the options in the order created
+func TestOptionsOrder(t *testing.T) {
+	p := NewParser()
+	z := p.NewFlag('z', "zebra", "last alphabetically")
+	a := p.NewOptArg('a', "apple", "first alphabetically")
+	m := p.NewOptCount(0, "mango", "in the middle")
+	f := p.NewOptFloat('f', "fig", "a float").AddAlias("ficus")
+	want := []Option{ z, a, m, f }
+	got := p.Options()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d options, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Expected --%s at %d, got --%s", optLong(want[i]), i, optLong(got[i]))
+		}
+	}
+	got[0] = nil
+	if p.Options()[0] != z {
+		t.Fatal("Expected Options to return a copy")
+	}
+}
+
+//Check that invalid names panic at registration
+func TestInvalidNamePanics(t *testing.T) {
+	cases := []struct {
+		short	byte
+		long	string
+		msg	string
+	}{
+		{ ' ', "space", "Invalid short option ' ' for --space" },
+		{ '-', "dash", "Invalid short option '-' for --dash" },
+		{ '+', "plus", "Invalid short option '+' for --plus" },
+		{ '=', "equals", "Invalid short option '=' for --equals" },
+		{ '\t', "tab", "Invalid short option '\\t' for --tab" },
+		{ 0xe9, "high", "Invalid short option 'é' for --high" },
+		{ 'x', "", "Invalid long option \"\"" },
+		{ 'x', "has spaces", "Invalid long option \"has spaces\"" },
+		{ 'x', "key=value", "Invalid long option \"key=value\"" },
+		{ 'x', "tab\there", "Invalid long option \"tab\\there\"" },
+	}
+	for _, c := range cases {
+		p := NewParser()
+		func() {
+			defer func() {
+				if r := recover(); r != c.msg {
+					t.Fatalf("Expected panic %q, got %v", c.msg, r)
+				}
+			}()
+			p.NewFlag(c.short, c.long, "invalid")
+		}()
+		if len(p.Options()) != 0 {
+			t.Fatalf("Expected %q not to be registered", c.msg)
+		}
+	}
+	p := NewParser()
+	q := p.NewFlag(0, "quiet", "no short name")
+	if err := p.ParseArgv([]string{ "--quiet" }); err != nil || !q.Passed {
+		t.Fatalf("Expected --quiet without a short name, got %v", err)
+	}
+	defer func() {
+		if r := recover(); r != "Invalid long option \"no=way\"" {
+			t.Fatalf("Expected panic for an invalid alias, got %v", r)
+		}
+	}()
+	q.AddAlias("no=way")
+}
+
+//Check that concurrent parses on the default parser run one at a
+//time rather than corrupting it
+func TestConcurrentParse(t *testing.T) {
+	Reset()
+	v := NewOptCount('v', "verbose", "verbosity")
+	f := NewOptArg('f', "file", "file to read")
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- ParseArgv([]string{ "-vv", "--file", "a.txt", "x", "y" })
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v.Count < 2 || f.Opt != "a.txt" || fmt.Sprint(Rest) != "[x y]" || OptInd != 3 {
+		t.Fatalf("Expected a consistent parse, got %d '%s' %v %d", v.Count, f.Opt, Rest, OptInd)
+	}
+	if err := defaultParser.AssertInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//Check that an unregistered option is no longer parsed, and that a
+//replacement can take its names
+func TestUnregister(t *testing.T) {
+	p := NewParser()
+	p.ProgramName = "prog"
+	v := p.NewFlag('v', "verbose", "print more").AddAlias("loud")
+	v.SetShortRune('é')
+	q := p.NewFlag('q', "quiet", "print less")
+	p.NewExclusiveGroup("noise", false, v, q)
+	if p.Unregister("nope") {
+		t.Fatal("Expected false for an unknown option")
+	}
+	if !p.Unregister("loud") {
+		t.Fatal("Expected --verbose to be removed by its alias")
+	}
+	if err := p.AssertInvariants(); err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range []string{ "-v", "--verbose", "--loud", "-é" } {
+		if err := p.ParseArgv([]string{ "-q", arg }); err == nil {
+			t.Fatalf("Expected %s to be unrecognized", arg)
+		}
+	}
+	if len(p.Options()) != 1 || len(p.exclusiveGroups[0].Opts) != 1 {
+		t.Fatalf("Expected only --quiet left, got %d options and %v", len(p.Options()), p.exclusiveGroups[0].Opts)
+	}
+	level := p.NewOptCount('v', "verbose", "verbosity").AddAlias("loud")
+	if err := p.ParseArgv([]string{ "-vv", "--loud" }); err != nil || level.Count != 3 || v.Passed {
+		t.Fatalf("Expected the replacement to be parsed, got %d %t, %v", level.Count, v.Passed, err)
+	}
+	if u := p.Usage(); u != "usage: prog [-v] [-q] [args...]" {
+		t.Fatalf("Expected the replacement in the usage, got %q", u)
+	}
+	if err := p.AssertInvariants(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,82 @@
+package getopt
+
+import(
+	"sort"
+	"time"
+)
+
+//If set, reading an option through its Get method is recorded,
+//so that UnreadOptions can report options that were passed but
+//never used by the program
+var TrackReads bool
+
+//Record that an option was read.  The parser is nil for options
+//not created by a constructor
+func (p *Parser) markRead(opt Option) {
+	if p != nil && TrackReads {
+		p.readOpts[opt] = true
+	}
+}
+
+//Return whether the flag is set
+func (f *Flag) Get() bool {
+	f.parser.markRead(f)
+	return f.Passed
+}
+
+//Return the argument of the option
+func (o *OptArg) Get() string {
+	o.parser.markRead(o)
+	return o.Opt
+}
+
+//Return the arguments of the option
+func (v *OptVec) Get() []string {
+	v.parser.markRead(v)
+	return v.OptArgs
+}
+
+//Return the count of the option
+func (c *OptCount) Get() int64 {
+	c.parser.markRead(c)
+	return c.Count
+}
+
+//Return the value of the option
+func (n *OptInt) Get() int64 {
+	n.parser.markRead(n)
+	return n.Value
+}
+
+//Return the value of the option
+func (x *OptFloat) Get() float64 {
+	x.parser.markRead(x)
+	return x.Value
+}
+
+//Return the value of the option
+func (d *OptDuration) Get() time.Duration {
+	d.parser.markRead(d)
+	return d.Value
+}
+
+//Return the long names of the options passed in the last parse
+//whose values were never read through their Get methods.  Only
+//meaningful when TrackReads is set
+func UnreadOptions() []string {
+	return defaultParser.UnreadOptions()
+}
+
+//Return the long names of the options passed in the last parse
+//whose values were never read through their Get methods.  Only
+//meaningful when TrackReads is set
+func (p *Parser) UnreadOptions() []string {
+	unread := make([]string, 0, initialCapacity)
+	for opt := range p.seenOpts {
+		if !p.readOpts[opt] {
+			unread = append(unread, optLong(opt))
+		}
+	}
+	sort.Strings(unread)
+	return unread
+}
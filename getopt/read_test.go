@@ -0,0 +1,29 @@
+package getopt
+
+import(
+	"testing"
+)
+
+//Check that passed options never read are reported
+func TestUnreadOptions(t *testing.T) {
+	Reset()
+	TrackReads = true
+	defer func() { TrackReads = false }()
+	a := NewFlag('a', "about", "topic")
+	f := NewOptArg('f', "file", "file to read")
+	NewOptCount('v', "verbose", "verbosity")
+	NewOptVec('I', "include", "include directory")
+	if err := ParseArgv([]string{ "-a", "-v", "--file=x", "-Idir" }); err != nil {
+		t.Fatalf("Expected no error, got %s", err)
+	}
+	if !a.Get() {
+		t.Fatal("'a' was passed, should be true")
+	}
+	if f.Get() != "x" {
+		t.Fatalf("Expected 'x', got %s", f.Get())
+	}
+	unread := UnreadOptions()
+	if len(unread) != 2 || unread[0] != "include" || unread[1] != "verbose" {
+		t.Fatalf("Expected [include verbose], got %v", unread)
+	}
+}
@@ -0,0 +1,99 @@
+package getopt
+
+import(
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Short names are bytes, so a non-ASCII letter such as 'é' cannot be
+//one.  SetShortRune gives an option such a letter as its short name
+//instead, which is then parsed like any other, e.g., "-é", "-vé",
+//"-éVALUE" and "+é"
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (f *Flag) SetShortRune(r rune) *Flag {
+	f.parser.setShortRune(f, r)
+	return f
+}
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (o *OptArg) SetShortRune(r rune) *OptArg {
+	o.parser.setShortRune(o, r)
+	return o
+}
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (v *OptVec) SetShortRune(r rune) *OptVec {
+	v.parser.setShortRune(v, r)
+	return v
+}
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (c *OptCount) SetShortRune(r rune) *OptCount {
+	c.parser.setShortRune(c, r)
+	return c
+}
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (n *OptInt) SetShortRune(r rune) *OptInt {
+	n.parser.setShortRune(n, r)
+	return n
+}
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (x *OptFloat) SetShortRune(r rune) *OptFloat {
+	x.parser.setShortRune(x, r)
+	return x
+}
+
+//Set a non-ASCII short name for the option, replacing any set
+//before.  Panics if the rune is ASCII, not printable, or taken
+func (d *OptDuration) SetShortRune(r rune) *OptDuration {
+	d.parser.setShortRune(d, r)
+	return d
+}
+
+//Give an option a non-ASCII short name
+func (p *Parser) setShortRune(opt Option, r rune) {
+	if r < utf8.RuneSelf || !unicode.IsPrint(r) || unicode.IsSpace(r) {
+		panic(fmt.Sprintf("Invalid short option %q for --%s", r, optLong(opt)))
+	}
+	if other, ok := p.optByRune[r]; ok {
+		panic(fmt.Sprintf("Duplicate short option -%c:  --%s and --%s", r, optLong(other), optLong(opt)))
+	}
+	if old, ok := p.shortRunes[opt]; ok {
+		delete(p.optByRune, old)
+	}
+	p.optByRune[r] = opt
+	p.shortRunes[opt] = r
+}
+
+//Find the short option named at the start of s, part of a group
+//of short options.  Returns the option, or nil if there is none,
+//and the length of its name in bytes
+func (p *Parser) lookupShort(s string) (Option, int) {
+	if s[0] < utf8.RuneSelf {
+		return p.optByShort[s[0]], 1
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	return p.optByRune[r], size
+}
+
+//Return the short name of an option, byte or rune, or "" if it has
+//none
+func (p *Parser) shortName(opt Option) string {
+	if optShort(opt) != 0 {
+		return string([]byte{ optShort(opt) })
+	}
+	if r, ok := p.shortRunes[opt]; ok {
+		return string(r)
+	}
+	return ""
+}
@@ -0,0 +1,85 @@
+package getopt
+
+import(
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+//Check that non-ASCII short names are parsed alone, in groups and
+//with attached values, without splitting runes
+func TestShortRune(t *testing.T) {
+	p := NewParser()
+	p.ProgramName, p.ProgramVersion = "prog", "1.0"
+	v := p.NewFlag('v', "verbose", "print more")
+	e := p.NewOptArg(0, "etiqueta", "label").SetShortRune('é')
+	n := p.NewOptCount(0, "nivel", "level").SetShortRune('ñ')
+	if err := p.ParseArgv([]string{ "-éfoo", "-vññ" }); err != nil || e.Opt != "foo" || !v.Passed || n.Count != 2 {
+		t.Fatalf("Expected foo, -v and 2, got '%s' %t %d, %v", e.Opt, v.Passed, n.Count, err)
+	}
+	if err := p.ParseArgv([]string{ "-vé", "ünï", "+ñ" }); err != nil || e.Opt != "ünï" || n.Count != 1 {
+		t.Fatalf("Expected ünï and 1, got '%s' %d, %v", e.Opt, n.Count, err)
+	}
+	if err := p.ParseArgv([]string{ "-éñé", "-ñ5" }); err != nil || e.Opt != "ñé" || n.Count != 5 {
+		t.Fatalf("Expected ñé and 5, got '%s' %d, %v", e.Opt, n.Count, err)
+	}
+	var unknown *UnknownOptionError
+	if err := p.ParseArgv([]string{ "-vü" }); !errors.As(err, &unknown) || unknown.Name != "ü" {
+		t.Fatalf("Expected ü to be unrecognized, got %v", err)
+	}
+	var b bytes.Buffer
+	p.writeHelp(&b)
+	expected := "prog - 1.0\n" +
+		"-é/--etiqueta  label\n" +
+		"-ñ/--nivel     level\n" +
+		"-v/--verbose   print more\n"
+	if b.String() != expected {
+		t.Fatalf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+	if u := p.Usage(); u != "usage: prog [-é ETIQUETA] [-ñ] [-v] [args...]" {
+		t.Fatalf("Expected the runes in the usage, got %q", u)
+	}
+}
+
+//Check that a rune short option can be passed alone, but not in a
+//group, when clumping is not allowed
+func TestShortRuneNoClumping(t *testing.T) {
+	AllowClumping = false
+	defer func() { AllowClumping = true }()
+	p := NewParser()
+	v := p.NewFlag('v', "verbose", "print more")
+	e := p.NewFlag(0, "extra", "extra output").SetShortRune('é')
+	l := p.NewOptArg(0, "label", "label").SetShortRune('ł')
+	if err := p.ParseArgv([]string{ "-é", "-łfoo" }); err != nil || !e.Passed || l.Opt != "foo" {
+		t.Fatalf("Expected -é and -łfoo to be allowed, got %t '%s', %v", e.Passed, l.Opt, err)
+	}
+	if err := p.ParseArgv([]string{ "+é" }); err != nil || e.Passed {
+		t.Fatalf("Expected +é to be allowed, got %t, %v", e.Passed, err)
+	}
+	for _, arg := range []string{ "-év", "+év", "+vé" } {
+		if err := p.ParseArgv([]string{ arg }); err == nil {
+			t.Fatalf("Expected %s to be refused without clumping", arg)
+		}
+	}
+	if v.Passed {
+		t.Fatal("Expected -v not to be set")
+	}
+}
+
+//Check that invalid and taken runes are refused
+func TestShortRuneInvalid(t *testing.T) {
+	p := NewParser()
+	e := p.NewFlag(0, "e", "e")
+	e.SetShortRune('é')
+	for _, r := range []rune{ 'x', ' ', 'é' } {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("Expected a panic for %q", r)
+				}
+			}()
+			p.NewFlag(0, fmt.Sprintf("opt%d", r), "another").SetShortRune(r)
+		}()
+	}
+}